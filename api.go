@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHost is used when neither --host nor GH_HOST is set
+const defaultHost = "github.com"
+
+// ghHost is the GitHub host every gh call goes to, e.g. a GitHub Enterprise
+// Server hostname
+var ghHost = defaultHost
+
+// defaultTimeout is how long a gh call may take unless --timeout or the
+// config says otherwise
+const defaultTimeout = 15 * time.Second
+
+// ghTimeout bounds every gh call, so a hung network can't freeze the UI
+var ghTimeout = defaultTimeout
+
+// ghCommand builds a gh invocation against ghHost, killed once ctx is done.
+// gh reads GH_HOST in every subcommand, whereas only some take --hostname.
+func ghCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Env = append(os.Environ(), "GH_HOST="+ghHost)
+	// Don't wait on output from anything gh started once gh itself is killed
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// webURL returns the address of path on ghHost's website
+func webURL(path string) string {
+	return "https://" + ghHost + "/" + path
+}
+
+// apiReposPrefix is the start of REST URLs for repository resources, which
+// GitHub Enterprise Server serves under /api/v3 on its own host
+func apiReposPrefix() string {
+	if ghHost == defaultHost {
+		return "https://api.github.com/repos/"
+	}
+	return "https://" + ghHost + "/api/v3/repos/"
+}
+
+// apiResponse is a REST response as printed by `gh api --include`
+type apiResponse struct {
+	status int
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// ghAPI runs `gh api --include` so callers can see the status and headers,
+// which gh otherwise hides. The response is returned even when gh reports
+// an HTTP error, alongside that error.
+func ghAPI(args ...string) (apiResponse, error) {
+	output, err := ghOutput(append([]string{"api", "--include"}, args...)...)
+	resp, parseErr := parseAPIResponse(output)
+	if parseErr != nil {
+		log.Printf("failed to parse response: %v", parseErr)
+	}
+	if err != nil {
+		return resp, err
+	}
+	return resp, parseErr
+}
+
+// parseAPIResponse splits gh's output into the status line, headers and body
+func parseAPIResponse(output []byte) (apiResponse, error) {
+	var resp apiResponse
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(output)))
+
+	statusLine, err := reader.ReadLine()
+	if err != nil {
+		return resp, fmt.Errorf("missing HTTP status line")
+	}
+	// e.g. "HTTP/2.0 200 OK"
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		return resp, fmt.Errorf("unexpected HTTP status line %q", statusLine)
+	}
+	resp.status, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return resp, fmt.Errorf("unexpected HTTP status line %q", statusLine)
+	}
+
+	resp.header, err = reader.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return resp, fmt.Errorf("failed to parse response headers: %v", err)
+	}
+
+	resp.body, err = io.ReadAll(reader.R)
+	if err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// maxErrorDetail caps how much of a response or stderr is quoted in errors,
+// which end up in the status line
+const maxErrorDetail = 80
+
+// firstLine returns the first non-blank line of output, truncated to
+// maxErrorDetail
+func firstLine(output []byte) string {
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		return truncate(line, maxErrorDetail)
+	}
+	return "(empty response)"
+}
+
+// ghErrorDetail returns the first line gh printed to stderr for a failed
+// command, or "" if there was none
+func ghErrorDetail(err error) string {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || len(bytes.TrimSpace(exitErr.Stderr)) == 0 {
+		return ""
+	}
+	return firstLine(exitErr.Stderr)
+}
+
+// hasNextPage reports whether the Link header points at another page
+func hasNextPage(header textproto.MIMEHeader) bool {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		if strings.Contains(link, `rel="next"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// ghOutput runs gh for its output, giving up after ghTimeout. It logs the
+// command and classifies authentication failures as authError.
+func ghOutput(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ghTimeout)
+	defer cancel()
+	cmd := ghCommand(ctx, args...)
+	output, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("request timed out after %s", ghTimeout)
+	}
+	logCommand(cmd, err)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// gh reports a rejected token as an HTTP 401, and a missing one
+		// by suggesting gh auth login
+		stderr := string(exitErr.Stderr)
+		if strings.Contains(stderr, "HTTP 401") || strings.Contains(stderr, "gh auth login") {
+			return output, authError{err: err}
+		}
+	}
+	return output, err
+}
+
+// authError is returned when gh's credentials are missing, expired or revoked
+type authError struct {
+	err error
+}
+
+func (e authError) Error() string {
+	if detail := ghErrorDetail(e.err); detail != "" {
+		return "authentication failed: " + detail
+	}
+	return "authentication failed: " + e.err.Error()
+}
+
+func (e authError) Unwrap() error {
+	return e.err
+}
+
+// isAuthError reports whether err came from an authentication failure
+func isAuthError(err error) bool {
+	var auth authError
+	return errors.As(err, &auth)
+}
+
+// rateLimitError is returned when GitHub refuses a request until reset
+type rateLimitError struct {
+	reset time.Time
+}
+
+func (e rateLimitError) Error() string {
+	return fmt.Sprintf("rate limited; retry at %s", e.reset.Format("15:04"))
+}
+
+// rateLimitFromResponse returns a rateLimitError if the response is a
+// primary or secondary rate limit rejection, or nil otherwise
+func rateLimitFromResponse(resp apiResponse) error {
+	if resp.status != 403 && resp.status != 429 {
+		return nil
+	}
+
+	// Secondary rate limits say how long to wait directly
+	if seconds, err := strconv.Atoi(resp.header.Get("Retry-After")); err == nil {
+		return rateLimitError{reset: time.Now().Add(time.Duration(seconds) * time.Second)}
+	}
+
+	// A 403 is only a rate limit if the quota is used up; otherwise it's
+	// a permissions problem
+	if resp.header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+	reset, err := strconv.ParseInt(resp.header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		// No reset time given; GitHub's guidance is to wait a minute
+		return rateLimitError{reset: time.Now().Add(time.Minute)}
+	}
+	return rateLimitError{reset: time.Unix(reset, 0)}
+}
+
+// rateLimitInfo is the REST API quota, as reported by the rate_limit endpoint
+type rateLimitInfo struct {
+	Remaining int `json:"remaining"`
+	Limit     int `json:"limit"`
+}
+
+// fetchRateLimit reads the core REST quota. Querying it doesn't count
+// against the quota.
+func fetchRateLimit() (rateLimitInfo, error) {
+	resp, err := ghAPI("rate_limit")
+	if err != nil {
+		return rateLimitInfo{}, err
+	}
+	var result struct {
+		Resources struct {
+			Core rateLimitInfo `json:"core"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return rateLimitInfo{}, fmt.Errorf("failed to parse rate limit: %v", err)
+	}
+	return result.Resources.Core, nil
+}
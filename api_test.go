@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGH puts a gh on PATH that runs script
+func fakeGH(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script gh")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestGHOutputTimesOut(t *testing.T) {
+	fakeGH(t, "exec sleep 5")
+	defer func(timeout time.Duration) { ghTimeout = timeout }(ghTimeout)
+	ghTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := ghOutput("api", "notifications")
+	if err == nil || !strings.Contains(err.Error(), "request timed out") {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %s to time out", elapsed)
+	}
+}
+
+func TestGHOutputAuthError(t *testing.T) {
+	fakeGH(t, "echo 'HTTP 401: Bad credentials' >&2; exit 1")
+
+	_, err := ghOutput("api", "notifications")
+	if !isAuthError(err) {
+		t.Errorf("err = %v, want an authError", err)
+	}
+}
+
+func TestFetchNotificationsPageEmptyBody(t *testing.T) {
+	fakeGH(t, `printf 'HTTP/2.0 200 OK\r\nContent-Length: 0\r\nX-Poll-Interval: 60\r\n\r\n'`)
+
+	batch, err := fetchNotificationsPage(1, notificationsQuery{}, "")
+	if err != nil || len(batch.notifications) != 0 || batch.hasMore {
+		t.Errorf("fetchNotificationsPage() = %+v, %v, want an empty last page", batch, err)
+	}
+	if batch.pollInterval != time.Minute {
+		t.Errorf("pollInterval = %s, want 1m0s from X-Poll-Interval", batch.pollInterval)
+	}
+}
+
+func TestFetchNotificationsPageNotModified(t *testing.T) {
+	// gh fails on a 304, having only the status line to print
+	fakeGH(t, `case "$*" in
+*'If-None-Match: "abc"'*) printf 'HTTP/2.0 304 Not Modified\r\nEtag: "abc"\r\n\r\n'; exit 1 ;;
+esac
+printf 'HTTP/2.0 200 OK\r\nEtag: "abc"\r\n\r\n[]'`)
+
+	batch, err := fetchNotificationsPage(1, notificationsQuery{}, "")
+	if err != nil || batch.etag != `"abc"` {
+		t.Fatalf("fetchNotificationsPage() = %+v, %v, want etag \"abc\"", batch, err)
+	}
+	if _, err := fetchNotificationsPage(1, notificationsQuery{}, batch.etag); !errors.Is(err, errNotModified) {
+		t.Errorf("conditional fetch err = %v, want errNotModified", err)
+	}
+}
+
+func TestFetchNotificationsPageForRepo(t *testing.T) {
+	fakeGH(t, `case "$*" in
+*' repos/cli/cli/notifications?'*) printf 'HTTP/2.0 200 OK\r\n\r\n[{"id": "1"}]' ;;
+*) echo "unexpected: $*" >&2; exit 1 ;;
+esac`)
+
+	batch, err := fetchNotificationsPage(1, notificationsQuery{repo: "cli/cli"}, "")
+	if err != nil || len(batch.notifications) != 1 {
+		t.Errorf("fetchNotificationsPage() = %+v, %v, want the repository's one notification", batch, err)
+	}
+}
+
+func TestValidateRepoName(t *testing.T) {
+	for _, name := range []string{"cli/cli", "my-org/repo.go", "a/b_c"} {
+		if err := validateRepoName(name); err != nil {
+			t.Errorf("validateRepoName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "cli", "cli/", "/cli", "a/b/c", "a/b?all=true"} {
+		if err := validateRepoName(name); err == nil {
+			t.Errorf("validateRepoName(%q) accepted it", name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// cachePath returns where the last fetched notifications are kept, honoring
+// XDG_CACHE_HOME. Hosts other than github.com get their own file.
+func cachePath() (string, error) {
+	name := "notifications.json"
+	if ghHost != defaultHost {
+		name = "notifications-" + ghHost + ".json"
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "ghn", name), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cache", "ghn", name), nil
+}
+
+// saveCache writes notifications to the cache file so the next launch can
+// show them before its fetch completes
+func saveCache(notifications []Notification) error {
+	path, err := cachePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(notifications)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write then rename so an interrupted save can't leave a truncated file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadCache reads the cached notifications. A missing or unreadable cache is
+// reported as not found rather than an error, since it's only a head start.
+func loadCache() ([]Notification, bool) {
+	path, err := cachePath()
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var notifications []Notification
+	if err := json.Unmarshal(data, &notifications); err != nil {
+		return nil, false
+	}
+	return notifications, true
+}
@@ -0,0 +1,95 @@
+package main
+
+import "strings"
+
+// GitHubClient is everything the app asks of GitHub. ghClient implements it
+// with the gh CLI; tests substitute a fake.
+type GitHubClient interface {
+	// NotificationsPage fetches one page of the inbox, counting from 1. Given
+	// the etag of an earlier fetch, it returns errNotModified if nothing
+	// changed since.
+	NotificationsPage(page int, query notificationsQuery, etag string) (notificationsPage, error)
+	MarkRead(id string) error
+	MarkDone(id string) error
+	Unsubscribe(id string) error
+	Subscribe(id string) error
+	PostComment(notification Notification, body string) error
+	OpenInBrowser(notification Notification) error
+	// OpenReview opens the "Files changed" tab of a pull request
+	OpenReview(notification Notification) error
+	// OpenRepoList opens the repository's issues or pull requests, whichever
+	// the notification is, or the repository itself for other subjects
+	OpenRepoList(notification Notification) error
+	// SubjectWebURL finds the web page for a notification's subject,
+	// reporting false if it has none
+	SubjectWebURL(notification Notification) (string, bool)
+	SubjectDetails(url string, subjectType string) (detailsLoadedMsg, error)
+	// SubjectsGraphQL looks up many subjects at once, keyed by Subject.URL
+	SubjectsGraphQL(notifications []Notification) (map[string]detailsLoadedMsg, error)
+	RateLimit() (rateLimitInfo, error)
+	// Viewer returns the authenticated user's login
+	Viewer() (string, error)
+}
+
+// ghClient talks to GitHub through the gh CLI, against ghHost
+type ghClient struct{}
+
+func (ghClient) NotificationsPage(page int, query notificationsQuery, etag string) (notificationsPage, error) {
+	return fetchNotificationsPage(page, query, etag)
+}
+
+func (ghClient) MarkRead(id string) error {
+	return markAsRead(id)
+}
+
+func (ghClient) MarkDone(id string) error {
+	return markAsDone(id)
+}
+
+func (ghClient) Unsubscribe(id string) error {
+	return unsubscribe(id)
+}
+
+func (ghClient) Subscribe(id string) error {
+	return subscribe(id)
+}
+
+func (ghClient) PostComment(notification Notification, body string) error {
+	return postComment(notification, body)
+}
+
+func (ghClient) OpenInBrowser(notification Notification) error {
+	return openInBrowser(notification)
+}
+
+func (ghClient) OpenReview(notification Notification) error {
+	return openReviewInBrowser(notification)
+}
+
+func (ghClient) OpenRepoList(notification Notification) error {
+	return openRepoListInBrowser(notification)
+}
+
+func (ghClient) SubjectWebURL(notification Notification) (string, bool) {
+	return subjectWebURL(notification)
+}
+
+func (ghClient) SubjectDetails(url string, subjectType string) (detailsLoadedMsg, error) {
+	return fetchDetails(url, subjectType)
+}
+
+func (ghClient) SubjectsGraphQL(notifications []Notification) (map[string]detailsLoadedMsg, error) {
+	return fetchSubjectsGraphQL(notifications)
+}
+
+func (ghClient) RateLimit() (rateLimitInfo, error) {
+	return fetchRateLimit()
+}
+
+func (ghClient) Viewer() (string, error) {
+	output, err := ghOutput("api", "user", "--jq", ".login")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,561 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeClient serves notifications from memory and records what was marked
+type fakeClient struct {
+	notifications []Notification
+	more          []Notification // served as page 2
+	fetchErr      error
+	markErr       error
+	marked        []string
+	done          []string
+	subscribed    []string
+	opened        []string           // IDs opened in the browser
+	reviewed      []string           // IDs whose "Files changed" tab was opened
+	repoLists     []string           // subject types whose repository list was opened
+	query         notificationsQuery // of the last fetch
+	etag          string             // sent with the last fetch
+	currentETag   string             // the inbox's; a fetch sending it gets a 304
+}
+
+func (c *fakeClient) NotificationsPage(page int, query notificationsQuery, etag string) (notificationsPage, error) {
+	c.query = query
+	c.etag = etag
+	if c.fetchErr != nil {
+		return notificationsPage{}, c.fetchErr
+	}
+	if page > 1 {
+		return notificationsPage{notifications: c.more}, nil
+	}
+	if etag != "" && etag == c.currentETag {
+		return notificationsPage{}, errNotModified
+	}
+	return notificationsPage{notifications: c.notifications, hasMore: len(c.more) > 0, etag: c.currentETag}, nil
+}
+
+func (c *fakeClient) MarkRead(id string) error {
+	if c.markErr != nil {
+		return c.markErr
+	}
+	c.marked = append(c.marked, id)
+	return nil
+}
+
+func (c *fakeClient) MarkDone(id string) error {
+	if c.markErr != nil {
+		return c.markErr
+	}
+	c.done = append(c.done, id)
+	return nil
+}
+
+func (c *fakeClient) Unsubscribe(id string) error {
+	return c.MarkRead(id)
+}
+
+func (c *fakeClient) Subscribe(id string) error {
+	if c.markErr != nil {
+		return c.markErr
+	}
+	c.subscribed = append(c.subscribed, id)
+	return nil
+}
+
+func (c *fakeClient) PostComment(Notification, string) error {
+	return nil
+}
+
+func (c *fakeClient) OpenInBrowser(notification Notification) error {
+	c.opened = append(c.opened, notification.ID)
+	return nil
+}
+
+func (c *fakeClient) OpenReview(notification Notification) error {
+	c.reviewed = append(c.reviewed, notification.ID)
+	return nil
+}
+
+func (c *fakeClient) OpenRepoList(notification Notification) error {
+	c.repoLists = append(c.repoLists, notification.Subject.Type)
+	return nil
+}
+
+func (c *fakeClient) SubjectWebURL(notification Notification) (string, bool) {
+	return apiToWebURL(notification.Subject.URL)
+}
+
+func (c *fakeClient) SubjectDetails(string, string) (detailsLoadedMsg, error) {
+	return detailsLoadedMsg{}, fmt.Errorf("not implemented")
+}
+
+func (c *fakeClient) SubjectsGraphQL([]Notification) (map[string]detailsLoadedMsg, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (c *fakeClient) RateLimit() (rateLimitInfo, error) {
+	return rateLimitInfo{}, fmt.Errorf("not implemented")
+}
+
+func (c *fakeClient) Viewer() (string, error) {
+	return "octocat", nil
+}
+
+// loadedModel starts the app against client and delivers its first fetch
+func loadedModel(t *testing.T, client GitHubClient) Model {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	m := initialModel(defaultConfig(), client, notificationsQuery{})
+	updated, _ := m.Update(fetchAttempt(client, m.config, m.query(), "", maxFetchRetries, nil))
+	return updated.(Model)
+}
+
+// press sends key to the model and feeds back the messages its command
+// produces
+func press(t *testing.T, m Model, key string) Model {
+	t.Helper()
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return run(updated.(Model), cmd)
+}
+
+// run feeds the messages cmd produces back into the model, one level deep
+func run(m Model, cmd tea.Cmd) Model {
+	if cmd == nil {
+		return m
+	}
+	msgs := []tea.Msg{cmd()}
+	if batch, ok := msgs[0].(tea.BatchMsg); ok {
+		msgs = msgs[:0]
+		for _, cmd := range batch {
+			if cmd != nil {
+				msgs = append(msgs, cmd())
+			}
+		}
+	}
+	for _, msg := range msgs {
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+	return m
+}
+
+func TestFetchLoadsFromClient(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{
+		{ID: "1", Unread: true, Subject: Subject{Title: "First"}},
+		{ID: "2", Unread: true, Subject: Subject{Title: "Second"}},
+	}}
+	m := loadedModel(t, client)
+
+	if m.loading {
+		t.Error("still loading after the fetch")
+	}
+	if got := visibleIDs(m); !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("visible = %v, want [1 2]", got)
+	}
+}
+
+func TestFetchErrorIsShown(t *testing.T) {
+	m := loadedModel(t, &fakeClient{fetchErr: fmt.Errorf("network down")})
+	if m.err == nil {
+		t.Fatal("err is nil after a failed fetch")
+	}
+}
+
+func TestMarkReadCallsClient(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{
+		{ID: "1", Unread: true},
+		{ID: "2", Unread: true},
+	}}
+	m := loadedModel(t, client)
+
+	m = press(t, m, "r")
+	if !slices.Equal(client.marked, []string{"1"}) {
+		t.Errorf("marked = %v, want [1]", client.marked)
+	}
+	if got := visibleIDs(m); !slices.Equal(got, []string{"2"}) {
+		t.Errorf("visible = %v, want [2]", got)
+	}
+}
+
+func TestMarkReadFailureKeepsNotification(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{{ID: "1", Unread: true}}}
+	m := loadedModel(t, client)
+	client.markErr = fmt.Errorf("server error")
+
+	m = press(t, m, "r")
+	if got := visibleIDs(m); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("visible = %v, want [1]", got)
+	}
+}
+
+func TestOpenReviewGoesThroughClient(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{
+		{ID: "1", Unread: true, Reason: "review_requested",
+			Subject: Subject{Type: "PullRequest", URL: "https://api.github.com/repos/cli/cli/pulls/7"}},
+		{ID: "2", Unread: true, Reason: "mention",
+			Subject: Subject{Type: "Issue", URL: "https://api.github.com/repos/cli/cli/issues/8"}},
+	}}
+	m := loadedModel(t, client)
+
+	m = press(t, m, "V")
+	m = press(t, m, "j")
+	press(t, m, "V")
+	if !slices.Equal(client.reviewed, []string{"1"}) || !slices.Equal(client.opened, []string{"2"}) {
+		t.Errorf("reviewed %v and opened %v, want [1] and [2]", client.reviewed, client.opened)
+	}
+}
+
+func TestOpenRepoListFollowsSubjectType(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{
+		{ID: "1", Unread: true, Subject: Subject{Type: "Issue"}},
+		{ID: "2", Unread: true, Subject: Subject{Type: "PullRequest"}},
+	}}
+	m := loadedModel(t, client)
+
+	m = press(t, m, "o")
+	m = press(t, m, "j")
+	press(t, m, "o")
+	if !slices.Equal(client.repoLists, []string{"Issue", "PullRequest"}) {
+		t.Errorf("opened lists for %v, want [Issue PullRequest]", client.repoLists)
+	}
+}
+
+func TestDryRunSkipsClient(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{{ID: "1", Unread: true}}}
+	m := loadedModel(t, client)
+	m.dryRun = true
+
+	m = press(t, m, "d")
+	if len(client.done) != 0 {
+		t.Errorf("done = %v in a dry run, want none", client.done)
+	}
+	if got := visibleIDs(m); len(got) != 0 {
+		t.Errorf("visible = %v, want none", got)
+	}
+}
+
+func TestShowAllRefetchesWithReadThreads(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{{ID: "1", Unread: true}}}
+	m := loadedModel(t, client)
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("N")})
+	m = updated.(Model)
+	if !m.showAll || !m.fetching {
+		t.Fatalf("showAll = %v, fetching = %v after N, want both", m.showAll, m.fetching)
+	}
+
+	client.notifications = append(client.notifications, Notification{ID: "2"})
+	updated, _ = m.Update(fetchAttempt(client, m.config, m.query(), "", maxFetchRetries, nil))
+	m = updated.(Model)
+	if !client.query.all || !m.listedQuery.all {
+		t.Errorf("fetched all = %v, listed all = %v, want both", client.query.all, m.listedQuery.all)
+	}
+	if strings.Contains(m.statusMessage, "cleared elsewhere") {
+		t.Errorf("status = %q after switching modes", m.statusMessage)
+	}
+
+	m = press(t, m, "j")
+	m = press(t, m, "S")
+	if !slices.Equal(client.subscribed, []string{"2"}) {
+		t.Errorf("subscribed = %v, want [2]", client.subscribed)
+	}
+}
+
+func TestParticipatingCombinesWithShowAll(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	client := &fakeClient{}
+	config := defaultConfig()
+	config.Participating = true
+	m := initialModel(config, client, notificationsQuery{all: true})
+
+	fetchAttempt(client, m.config, m.query(), "", maxFetchRetries, nil)
+	if want := (notificationsQuery{all: true, participating: true}); client.query != want {
+		t.Errorf("query = %+v, want %+v", client.query, want)
+	}
+}
+
+func TestCacheOnlyShownForInbox(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	if err := saveCache([]Notification{{ID: "1", Unread: true}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		query      notificationsQuery
+		wantCached bool
+	}{
+		{"inbox", notificationsQuery{}, true},
+		{"all threads", notificationsQuery{all: true}, false},
+		{"one repository", notificationsQuery{repo: "cli/cli"}, false},
+	}
+	for _, tt := range tests {
+		m := initialModel(defaultConfig(), &fakeClient{}, tt.query)
+		if m.cached != tt.wantCached || len(m.notifications) > 0 != tt.wantCached {
+			t.Errorf("%s: cached = %v with %d notifications, want cached %v",
+				tt.name, m.cached, len(m.notifications), tt.wantCached)
+		}
+		if m.query().all != tt.query.all || m.query().repo != tt.query.repo {
+			t.Errorf("%s: query() = %+v, want %+v", tt.name, m.query(), tt.query)
+		}
+	}
+}
+
+func TestPendingActionsShownUntilAnswered(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{{ID: "1", Unread: true}, {ID: "2", Unread: true}}}
+	m := loadedModel(t, client)
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = updated.(Model)
+	if m.pendingActions() != 1 || !strings.Contains(m.View(), "(1 action pending)") {
+		t.Fatalf("pendingActions() = %d before the reply, want 1 shown", m.pendingActions())
+	}
+
+	m = run(m, cmd)
+	if m.pendingActions() != 0 || strings.Contains(m.View(), "pending)") {
+		t.Errorf("pendingActions() = %d after the reply, want none shown", m.pendingActions())
+	}
+}
+
+func TestDefaultFilterAppliesToListAndLaunch(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	client := &fakeClient{notifications: []Notification{
+		{ID: "1", Unread: true, Reason: "review_requested", Subject: Subject{Title: "Wanted"}},
+		{ID: "2", Unread: true, Reason: "subscribed", Subject: Subject{Title: "Noise"}},
+		{ID: "3", Reason: "review_requested", Subject: Subject{Title: "Already read"}},
+	}}
+	config := defaultConfig()
+	config.DefaultFilter = DefaultFilter{Reason: "review_requested"}
+
+	var out strings.Builder
+	if err := runList(&out, client, config, notificationsQuery{}, false, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.Contains(got, "Wanted") || strings.Contains(got, "Noise") || strings.Contains(got, "Already read") {
+		t.Errorf("--list --unread-only printed:\n%s", got)
+	}
+
+	m := initialModel(config, client, notificationsQuery{})
+	updated, _ := m.Update(fetchAttempt(client, m.config, m.query(), "", maxFetchRetries, nil))
+	if got := visibleIDs(updated.(Model)); !slices.Equal(got, []string{"1", "3"}) {
+		t.Errorf("visible = %v, want [1 3]", got)
+	}
+}
+
+func TestAutoRefreshKeepsListWhenUnchanged(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{{ID: "1", Unread: true}}, currentETag: `"v1"`}
+	m := loadedModel(t, client)
+	if got := m.refreshETag(); got != `"v1"` {
+		t.Fatalf("refreshETag() = %q, want the last fetch's", got)
+	}
+
+	client.notifications = nil // only a full fetch would see this
+	updated, _ := m.Update(fetchAttempt(client, m.config, m.query(), m.refreshETag(), 0, nil))
+	m = updated.(Model)
+	if got := visibleIDs(m); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("visible = %v, want [1] kept", got)
+	}
+
+	// Switching to all threads leaves the etag behind
+	m.showAll = true
+	if got := m.refreshETag(); got != "" {
+		t.Errorf("refreshETag() = %q after N, want none", got)
+	}
+}
+
+func TestAutoRefreshRefetchesMultiPageInbox(t *testing.T) {
+	client := &fakeClient{
+		notifications: []Notification{{ID: "1", Unread: true}},
+		more:          []Notification{{ID: "2", Unread: true}},
+		currentETag:   `"v1"`,
+	}
+	m := loadedModel(t, client)
+	if got := m.refreshETag(); got != "" {
+		t.Fatalf("refreshETag() = %q, want none for a two-page inbox", got)
+	}
+
+	// Page 1 is unchanged, so its etag alone would hide this
+	client.more = []Notification{{ID: "3", Unread: true}}
+	updated, _ := m.Update(fetchAttempt(client, m.config, m.query(), m.refreshETag(), 0, nil))
+	if got := visibleIDs(updated.(Model)); !slices.Equal(got, []string{"1", "3"}) {
+		t.Errorf("visible = %v, want [1 3]", got)
+	}
+}
+
+func TestRefreshDropsPageInFlight(t *testing.T) {
+	m := loadedModel(t, &fakeClient{notifications: []Notification{{ID: "1", Unread: true}}})
+	m.hasMore = true
+	if m.loadMoreIfNearEnd() == nil {
+		t.Fatal("loadMoreIfNearEnd() fetched nothing")
+	}
+	stale := pageLoadedMsg{gen: m.pageGen, page: 2, notifications: []Notification{{ID: "old"}}}
+
+	updated, _ := m.Update(notificationsLoadedMsg{notifications: []Notification{{ID: "2", Unread: true}}, hasMore: true})
+	m = updated.(Model)
+	if m.pageLoading {
+		t.Error("pageLoading still set after a refresh")
+	}
+	updated, _ = m.Update(stale)
+	m = updated.(Model)
+	if got := visibleIDs(m); !slices.Equal(got, []string{"2"}) {
+		t.Errorf("visible = %v, want the stale page dropped", got)
+	}
+}
+
+func TestOverviewScrollsInsteadOfClipping(t *testing.T) {
+	var notifications []Notification
+	for i := range 30 {
+		notifications = append(notifications, Notification{
+			ID: fmt.Sprint(i), Unread: true, Repository: Repository{FullName: fmt.Sprintf("cli/repo%d", i)},
+		})
+	}
+	m := loadedModel(t, &fakeClient{notifications: notifications})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m = press(t, updated.(Model), "i")
+
+	view := m.View()
+	if lines := strings.Count(view, "\n") + 1; lines > 20 {
+		t.Errorf("overview is %d lines, want it to fit 20", lines)
+	}
+	if !strings.Contains(view, "Inbox Overview") || strings.Contains(view, "cli/repo29") {
+		t.Fatalf("overview = %q, want the title and the last repository off screen", view)
+	}
+
+	m = press(t, m, "G")
+	if view := m.View(); !strings.Contains(view, "Inbox Overview") || !strings.Contains(view, "cli/repo29") {
+		t.Errorf("overview after G = %q, want the title and the last repository", view)
+	}
+}
+
+func TestRepoPickerSurvivesShrinkingList(t *testing.T) {
+	inRepo := func(id, repo string) Notification {
+		return Notification{ID: id, Unread: true, Repository: Repository{FullName: repo}}
+	}
+	m := loadedModel(t, &fakeClient{notifications: []Notification{inRepo("1", "cli/cli"), inRepo("2", "cli/go-gh")}})
+	updated, _ := m.openRepoPicker()
+	m = press(t, updated.(Model), "G")
+
+	updated, _ = m.Update(notificationsLoadedMsg{notifications: []Notification{inRepo("1", "cli/cli")}})
+	m = updated.(Model)
+	if count := len(m.repoPickerEntries()); m.repoPickerIndex != count-1 {
+		t.Errorf("repoPickerIndex = %d, want the last of %d entries", m.repoPickerIndex, count)
+	}
+	m.View()
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := updated.(Model).repoFilter; got != "cli/cli" {
+		t.Errorf("repoFilter = %q, want cli/cli", got)
+	}
+}
+
+func TestPollIntervalOverridesShorterRefresh(t *testing.T) {
+	m := loadedModel(t, &fakeClient{})
+	m.refreshInterval = 30 * time.Second
+
+	updated, _ := m.Update(notificationsLoadedMsg{pollInterval: time.Minute})
+	m = updated.(Model)
+	if got := m.refreshEvery(); got != time.Minute {
+		t.Errorf("refreshEvery() = %s, want GitHub's 1m0s", got)
+	}
+	m.refreshInterval = 5 * time.Minute
+	if got := m.refreshEvery(); got != 5*time.Minute {
+		t.Errorf("refreshEvery() = %s, want the configured 5m0s", got)
+	}
+}
+
+func TestRawJSONOpensInSummaryPane(t *testing.T) {
+	m := loadedModel(t, &fakeClient{notifications: []Notification{{ID: "42", Unread: true}}})
+
+	m = press(t, m, "J")
+	if !m.showingSummary || !strings.Contains(m.summaryViewport.View(), `"id": "42"`) {
+		t.Fatalf("summary pane = %v, %q, want the raw JSON", m.showingSummary, m.summaryViewport.View())
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if updated.(Model).showingSummary {
+		t.Error("esc didn't return to the list")
+	}
+}
+
+func TestSummaryPaneScrolls(t *testing.T) {
+	m := loadedModel(t, &fakeClient{notifications: []Notification{{ID: "1", Unread: true}}})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 10})
+	m = press(t, updated.(Model), "J")
+	if !m.summaryViewport.AtTop() || m.summaryViewport.AtBottom() {
+		t.Fatal("raw JSON should open at the top with more below")
+	}
+
+	m = press(t, m, "G")
+	if !m.summaryViewport.AtBottom() {
+		t.Error("G didn't scroll to the bottom")
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyPgUp})
+	m = updated.(Model)
+	if m.summaryViewport.AtBottom() {
+		t.Error("pgup didn't scroll up")
+	}
+	m = press(t, m, "g")
+	if !m.summaryViewport.AtTop() {
+		t.Error("g didn't scroll to the top")
+	}
+}
+
+func TestFocusRestoresFilters(t *testing.T) {
+	client := &fakeClient{notifications: []Notification{
+		{ID: "1", Unread: true, Reason: "mention"},
+		{ID: "2", Unread: true, Reason: "subscribed"},
+		{ID: "3", Reason: "review_requested"},
+	}}
+	m := loadedModel(t, client)
+	m.typeFilter = "issue"
+	m.sortMode = sortOldest
+
+	m = press(t, m, "F")
+	if got := visibleIDs(m); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("focus visible = %v, want [1]", got)
+	}
+	m = press(t, m, "F")
+	if m.typeFilter != "issue" || m.sortMode != sortOldest || m.focusMode {
+		t.Errorf("filters after focus = %q, %q, want issue, oldest", m.typeFilter, m.sortMode)
+	}
+}
+
+func TestTinyTerminalAsksForRoom(t *testing.T) {
+	m := loadedModel(t, &fakeClient{notifications: []Notification{{ID: "1", Unread: true}}})
+
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 10, Height: 5})
+	if view := updated.(Model).View(); !strings.Contains(view, "Terminal") || strings.Contains(view, "GitHub Notifications") {
+		t.Errorf("10x5 view = %q, want only the too small note", view)
+	}
+	updated, _ = updated.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	if view := updated.(Model).View(); !strings.Contains(view, "GitHub Notifications") {
+		t.Errorf("view after resizing = %q, want the list", view)
+	}
+}
+
+func TestSnoozeMenu(t *testing.T) {
+	m := loadedModel(t, &fakeClient{notifications: []Notification{{ID: "1", Unread: true}, {ID: "2", Unread: true}}})
+
+	m = press(t, m, "z")
+	if !m.showingSnooze {
+		t.Fatal("z didn't open the snooze menu")
+	}
+	m = press(t, m, "j")
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	if m.showingSnooze {
+		t.Error("the menu stayed open after choosing")
+	}
+	if wake := m.snoozed["1"]; wake.Sub(time.Now()) < 3*time.Hour {
+		t.Errorf("snoozed until %s, want about 4 hours away", wake)
+	}
+	if got := visibleIDs(m); !slices.Equal(got, []string{"2"}) {
+		t.Errorf("visible = %v, want [2]", got)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// commentPostedMsg reports the result of posting a comment
+type commentPostedMsg struct {
+	err error
+}
+
+// commentsURL finds the REST endpoint for comments on an issue or pull
+// request's subject URL. Pull request conversation comments are posted
+// through the issues API.
+func commentsURL(subjectURL string) (string, bool) {
+	prefix := apiReposPrefix()
+	if !strings.HasPrefix(subjectURL, prefix) {
+		return "", false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(subjectURL, prefix), "/"), "/")
+	if len(parts) != 4 || parts[3] == "" {
+		return "", false
+	}
+	owner, repo, kind, number := parts[0], parts[1], parts[2], parts[3]
+	if kind != "issues" && kind != "pulls" {
+		return "", false
+	}
+	return fmt.Sprintf("%s%s/%s/issues/%s/comments", prefix, owner, repo, number), true
+}
+
+// postComment adds a comment to an issue or pull request
+func postComment(notification Notification, body string) error {
+	url, ok := commentsURL(notification.Subject.URL)
+	if !ok {
+		return fmt.Errorf("no comments endpoint for %s", notification.Subject.Type)
+	}
+
+	_, err := ghOutput("api",
+		"--method", "POST",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: 2022-11-28",
+		url,
+		"-f", "body="+body)
+	if err != nil {
+		if detail := ghErrorDetail(err); detail != "" && !isAuthError(err) {
+			return fmt.Errorf("%s", detail)
+		}
+		return err
+	}
+	return nil
+}
+
+func postCommentCmd(client GitHubClient, notification Notification, body string, dryRun bool) tea.Cmd {
+	return func() tea.Msg {
+		if dryRun {
+			log.Printf("dry run: comment on %s: %q", notification.Subject.URL, body)
+			return commentPostedMsg{}
+		}
+		if err := client.PostComment(notification, body); err != nil {
+			if isAuthError(err) {
+				return authErrorMsg{err: err, pending: true}
+			}
+			return commentPostedMsg{err: fmt.Errorf("failed to comment: %v", err)}
+		}
+		return commentPostedMsg{}
+	}
+}
+
+// commentWidth is the comment box's text width, leaving room for its border
+func (m Model) commentWidth() int {
+	return max(min(m.terminalWidth-10, 72), 10)
+}
+
+// openComment starts a comment on the selected issue or pull request
+func (m Model) openComment() (tea.Model, tea.Cmd) {
+	notification, ok := m.selectedNotification()
+	if !ok {
+		return m, nil
+	}
+	if notification.Subject.Type != "Issue" && notification.Subject.Type != "PullRequest" {
+		m.statusMessage = "Only issues and pull requests can be commented on"
+		return m, nil
+	}
+
+	input := textarea.New()
+	input.Placeholder = "Leave a comment"
+	input.ShowLineNumbers = false
+	input.CharLimit = 0
+	input.SetWidth(m.commentWidth())
+	input.SetHeight(6)
+	// A steady cursor saves threading blink messages through Update
+	input.Cursor.SetMode(cursor.CursorStatic)
+	input.Focus()
+
+	m.commenting = true
+	m.commentInput = input
+	m.commentTarget = notification
+	return m, nil
+}
+
+// handleCommentKey edits the comment. Enter adds a line, so posting has its
+// own key.
+func (m Model) handleCommentKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.commenting = false
+		m.statusMessage = "Comment discarded"
+		return m, nil
+	case "ctrl+s":
+		body := strings.TrimSpace(m.commentInput.Value())
+		if body == "" {
+			return m, nil
+		}
+		m.commenting = false
+		m.statusMessage = "Posting comment..."
+		cmd := m.trackAction(postCommentCmd(m.client, m.commentTarget, body, m.dryRun))
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.commentInput, cmd = m.commentInput.Update(msg)
+	return m, cmd
+}
+
+// commentView renders the comment box centered on screen
+func (m Model) commentView() string {
+	var content strings.Builder
+	content.WriteString(titleStyle.Render("Comment"))
+	content.WriteString("\n")
+	content.WriteString(dimStyle.Render(ansi.Truncate(m.commentTarget.Subject.Title, m.commentWidth(), "...")))
+	content.WriteString("\n\n")
+	content.WriteString(m.commentInput.View())
+	content.WriteString("\n\n")
+	content.WriteString(dimStyle.Render("^S:Post  Esc:Discard"))
+
+	return lipgloss.Place(m.terminalWidth, m.terminalHeight,
+		lipgloss.Center, lipgloss.Center,
+		summaryBoxStyle.Render(content.String()))
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds user settings loaded from ~/.config/ghn/config.yaml
+type Config struct {
+	// Theme is auto, dark or light; auto picks by terminal background
+	Theme string `yaml:"theme"`
+
+	// StatusFormat is a template for the status line, see statusPlaceholders
+	StatusFormat string `yaml:"status_format"`
+
+	// PostFetchCmd is a shell command that receives the fetched
+	// notifications as JSON on stdin and writes the list to show on stdout
+	PostFetchCmd string `yaml:"post_fetch_cmd"`
+
+	Confirmations Confirmations `yaml:"confirmations"`
+
+	// AutoRefresh starts with auto-refresh on, polling every RefreshInterval
+	AutoRefresh     bool          `yaml:"auto_refresh"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+
+	// Mouse enables clicking and scrolling the list. It's on by default, but
+	// turning it off restores the terminal's own text selection.
+	Mouse bool `yaml:"mouse"`
+
+	// CursorAfterMark is where the cursor goes when the notification under it
+	// is marked read or done: the next one (which moves up into its place) or
+	// the previous one
+	CursorAfterMark string `yaml:"cursor_after_mark"`
+
+	// Participating only fetches threads the user is directly involved in,
+	// e.g. mentioned, assigned or commenting, rather than everything they're
+	// subscribed to. GitHub applies it, so less is fetched.
+	Participating bool `yaml:"participating"`
+
+	// InfiniteScroll fetches only the first page of notifications, loading
+	// the next as the cursor nears the end of the list
+	InfiniteScroll bool `yaml:"infinite_scroll"`
+
+	// UseGraphQL looks up issue and pull request details in batched GraphQL
+	// queries instead of a REST request each, falling back to REST on error
+	UseGraphQL bool `yaml:"use_graphql"`
+
+	// DesktopNotifications raises an OS notification for items that arrive
+	// during auto-refresh
+	DesktopNotifications bool `yaml:"desktop_notifications"`
+
+	// Timeout is how long any one gh call may take before it's abandoned;
+	// --timeout overrides it
+	Timeout time.Duration `yaml:"timeout"`
+
+	// ImportantReasons are the reasons whose rows are flagged and their
+	// reason shown in bold capitals, e.g. [assign, review_requested]
+	ImportantReasons []string `yaml:"important_reasons"`
+
+	// BotPattern is a regular expression matched against subject authors'
+	// logins to hide them with hide_bots, on top of GitHub's own Bot
+	// accounts
+	BotPattern string `yaml:"bot_pattern"`
+
+	// DefaultFilter is what the list is filtered by at launch, and what
+	// --list prints
+	DefaultFilter DefaultFilter `yaml:"default_filter"`
+
+	// Keys rebinds actions, e.g. mark_read: [x]. Each listed action's
+	// default keys are replaced; see defaultKeys for the action names.
+	Keys map[string][]string `yaml:"keys"`
+
+	keyMap     KeyMap         // built from Keys by loadConfig
+	botPattern *regexp.Regexp // compiled from BotPattern by loadConfig
+}
+
+// Confirmations toggles the yes/no prompts guarding easy-to-regret actions
+type Confirmations struct {
+	// QuitWithSelection asks before quitting with a non-empty multi-select
+	QuitWithSelection bool `yaml:"quit_with_selection"`
+
+	// QuitWithPending asks before quitting while mark or unsubscribe
+	// requests are still in flight
+	QuitWithPending bool `yaml:"quit_with_pending"`
+
+	// BulkActions asks before marking every visible notification read or
+	// opening many tabs at once
+	BulkActions bool `yaml:"bulk_actions"`
+}
+
+// DefaultFilter sets the type, reason and unread filters the list starts
+// with. Empty fields don't filter.
+type DefaultFilter struct {
+	Type       string `yaml:"type"`   // a type filter, e.g. pr
+	Reason     string `yaml:"reason"` // a notification reason, e.g. review_requested
+	UnreadOnly bool   `yaml:"unread_only"`
+}
+
+// defaultBotPattern matches app accounts' logins as the REST API gives them,
+// e.g. dependabot[bot]
+const defaultBotPattern = `\[bot\]$`
+
+// isBot reports whether a subject author is a bot, by GitHub's account type
+// or bot_pattern
+func (c Config) isBot(login, accountType string) bool {
+	if accountType == "Bot" {
+		return true
+	}
+	return login != "" && c.botPattern != nil && c.botPattern.MatchString(login)
+}
+
+// Values for cursor_after_mark
+const (
+	cursorNext     = "next"
+	cursorPrevious = "previous"
+)
+
+func defaultConfig() Config {
+	cfg := Config{
+		Theme:        "auto",
+		StatusFormat: "{status}",
+		Confirmations: Confirmations{
+			QuitWithSelection: true,
+			QuitWithPending:   true,
+			BulkActions:       true,
+		},
+		RefreshInterval:      60 * time.Second,
+		DesktopNotifications: true,
+		CursorAfterMark:      cursorNext,
+		Mouse:                true,
+		Timeout:              defaultTimeout,
+		ImportantReasons:     []string{"assign", "review_requested"},
+		BotPattern:           defaultBotPattern,
+	}
+	cfg.keyMap, _ = newKeyMap(nil) // the defaults are always valid
+	cfg.botPattern = regexp.MustCompile(defaultBotPattern)
+	return cfg
+}
+
+// configPath returns the location of the config file, honoring XDG_CONFIG_HOME
+func configPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "ghn", "config.yaml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "ghn", "config.yaml"), nil
+}
+
+// loadConfig reads the config file on top of the defaults. A missing file is
+// not an error.
+func loadConfig() (Config, error) {
+	cfg := defaultConfig()
+
+	path, err := configPath()
+	if err != nil {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config %s: %v", path, err)
+	}
+
+	// Reject unknown settings so typos don't go silently unnoticed
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	cfg.keyMap, err = newKeyMap(cfg.Keys)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	cfg.botPattern, err = regexp.Compile(cfg.BotPattern)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid config %s: bot_pattern: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+func (c Config) validate() error {
+	if !validTheme(c.Theme) {
+		return fmt.Errorf("unknown theme %q, expected one of %s", c.Theme, strings.Join(themeNames, ", "))
+	}
+	if c.CursorAfterMark != cursorNext && c.CursorAfterMark != cursorPrevious {
+		return fmt.Errorf("cursor_after_mark must be %s or %s, got %q", cursorNext, cursorPrevious, c.CursorAfterMark)
+	}
+	// Anything shorter would poll continuously, e.g. a mistyped 60ns
+	if c.RefreshInterval < time.Second {
+		return fmt.Errorf("refresh_interval must be at least 1s, got %s", c.RefreshInterval)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive, got %s", c.Timeout)
+	}
+	if !slices.Contains(typeFilters, c.DefaultFilter.Type) {
+		return fmt.Errorf("unknown default_filter type %q, expected one of %s",
+			c.DefaultFilter.Type, strings.Join(typeFilters[1:], ", "))
+	}
+	return validateStatusFormat(c.StatusFormat)
+}
+
+// Status line templates
+
+// statusPlaceholders are the fields available to status_format
+var statusPlaceholders = []string{
+	"status",       // the latest status message
+	"unread",       // unread count of the visible list
+	"total",        // size of the visible list
+	"filter",       // active filters, or "none"
+	"last_refresh", // time of the last successful fetch
+	"sort",         // active sort order
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+func validateStatusFormat(format string) error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(format, -1) {
+		known := false
+		for _, name := range statusPlaceholders {
+			if match[1] == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown status_format placeholder {%s}, expected one of {%s}",
+				match[1], strings.Join(statusPlaceholders, "}, {"))
+		}
+	}
+	return nil
+}
+
+// renderStatusFormat fills in each placeholder from values
+func renderStatusFormat(format string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(format, func(placeholder string) string {
+		return values[placeholder[1:len(placeholder)-1]]
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startDebugLog sends log output to ghn.log beside the notification cache.
+// Without --debug, logging is discarded: stderr would corrupt the TUI.
+func startDebugLog(enabled bool) (io.Closer, error) {
+	if !enabled {
+		log.SetOutput(io.Discard)
+		return io.NopCloser(nil), nil
+	}
+	path, err := cachePath()
+	if err != nil {
+		return nil, err
+	}
+	path = filepath.Join(filepath.Dir(path), "ghn.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := tea.LogToFile(path, "ghn ")
+	if err != nil {
+		return nil, err
+	}
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	log.Printf("started %s against %s", versionString(), ghHost)
+	return file, nil
+}
+
+// logCommand records a finished command and how it went
+func logCommand(cmd *exec.Cmd, err error) {
+	args := strings.Join(cmd.Args, " ")
+	if err == nil {
+		log.Printf("ran %s: ok", args)
+		return
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		log.Printf("ran %s: exit status %d: %s", args, exitErr.ExitCode(), strings.TrimSpace(string(exitErr.Stderr)))
+		return
+	}
+	log.Printf("ran %s: %v", args, err)
+}
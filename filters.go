@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// listFilter is one stage of the pipeline visibleNotifications runs the inbox
+// through. Filters only ever narrow the list, so they compose in any order.
+type listFilter struct {
+	label  string // for the status line's {filter}, e.g. "type:pr"
+	status string // for filter status messages, e.g. "type pr"
+	keep   func(Notification) bool
+}
+
+// activeFilters lists the filters currently narrowing the list, with the
+// search query last
+func (m Model) activeFilters() []listFilter {
+	var filters []listFilter
+	if m.typeFilter != "" {
+		filters = append(filters, listFilter{
+			label:  "type:" + m.typeFilter,
+			status: "type " + m.typeFilter,
+			keep:   func(n Notification) bool { return n.TypeDisplay() == m.typeFilter },
+		})
+	}
+	if m.reasonFilter != "" {
+		filters = append(filters, listFilter{
+			label:  "reason:" + m.reasonFilter,
+			status: "reason " + renderReason(m.reasonFilter),
+			keep:   func(n Notification) bool { return n.Reason == m.reasonFilter },
+		})
+	}
+	if m.repoFilter != "" {
+		filters = append(filters, listFilter{
+			label:  "repo:" + m.repoFilter,
+			status: "repo " + m.repoFilter,
+			keep:   func(n Notification) bool { return n.RepoName() == m.repoFilter },
+		})
+	}
+	if m.unreadOnly {
+		filters = append(filters, listFilter{
+			label:  "unread",
+			status: "unread only",
+			keep:   func(n Notification) bool { return n.Unread },
+		})
+	}
+	if m.ageFilter != "" {
+		now := time.Now()
+		filters = append(filters, listFilter{
+			label:  "age:" + m.ageFilter,
+			status: "age " + m.ageFilter,
+			keep:   func(n Notification) bool { return ageMatches(m.ageFilter, n.UpdatedAt, now) },
+		})
+	}
+	if m.hideBots {
+		filters = append(filters, listFilter{
+			label:  "no-bots",
+			status: "hiding bots",
+			keep: func(n Notification) bool {
+				// Kept until its author is known
+				known := m.subjectStates[n.Subject.URL]
+				return !m.config.isBot(known.author, known.authorType)
+			},
+		})
+	}
+	if m.focusMode {
+		filters = append(filters, listFilter{
+			label:  "focus",
+			status: "focus",
+			keep: func(n Notification) bool {
+				return n.Unread && slices.Contains(focusReasons, n.Reason)
+			},
+		})
+	}
+	if m.searchQuery != "" {
+		query := strings.ToLower(m.searchQuery)
+		filters = append(filters, listFilter{
+			label:  "search:" + m.searchQuery,
+			status: fmt.Sprintf("search %q", m.searchQuery),
+			keep: func(n Notification) bool {
+				return strings.Contains(strings.ToLower(n.Subject.Title), query) ||
+					strings.Contains(strings.ToLower(n.RepoName()), query)
+			},
+		})
+	}
+	return filters
+}
+
+// focusReasons are what focus mode keeps: the notifications that need you
+var focusReasons = []string{"mention", "review_requested"}
+
+// filterState is every filter and the sort order, as focus mode sets them
+// aside and restores them
+type filterState struct {
+	typeFilter   string
+	reasonFilter string
+	repoFilter   string
+	ageFilter    string
+	unreadOnly   bool
+	hideBots     bool
+	searchQuery  string
+	sortMode     string
+}
+
+func (m Model) filters() filterState {
+	return filterState{
+		typeFilter:   m.typeFilter,
+		reasonFilter: m.reasonFilter,
+		repoFilter:   m.repoFilter,
+		ageFilter:    m.ageFilter,
+		unreadOnly:   m.unreadOnly,
+		hideBots:     m.hideBots,
+		searchQuery:  m.searchQuery,
+		sortMode:     m.sortMode,
+	}
+}
+
+func (m *Model) setFilters(f filterState) {
+	m.typeFilter = f.typeFilter
+	m.reasonFilter = f.reasonFilter
+	m.repoFilter = f.repoFilter
+	m.ageFilter = f.ageFilter
+	m.unreadOnly = f.unreadOnly
+	m.hideBots = f.hideBots
+	m.searchQuery = f.searchQuery
+	m.sortMode = f.sortMode
+}
+
+// toggleFocus switches focus mode, which starts from no other filters and
+// newest first, and puts the earlier filters back when it's turned off
+func (m *Model) toggleFocus() {
+	if m.focusMode {
+		m.focusMode = false
+		m.setFilters(m.preFocus)
+		return
+	}
+	m.preFocus = m.filters()
+	m.setFilters(filterState{sortMode: sortNewest})
+	m.focusMode = true
+}
+
+// keep reports whether a notification passes the default filter, for --list
+func (f DefaultFilter) keep(n Notification) bool {
+	return (f.Type == "" || n.TypeDisplay() == f.Type) &&
+		(f.Reason == "" || n.Reason == f.Reason) &&
+		(!f.UnreadOnly || n.Unread)
+}
+
+// visibleNotifications returns the notifications that aren't snoozed and
+// pass every active filter, in the active sort order. The view, navigation
+// and bulk actions all work on this list.
+func (m Model) visibleNotifications() []Notification {
+	filters := m.activeFilters()
+	visible := make([]Notification, 0, len(m.notifications))
+	now := time.Now()
+	for _, notification := range m.notifications {
+		if wake, ok := m.snoozed[notification.ID]; ok && now.Before(wake) {
+			continue
+		}
+		if passesAll(filters, notification) {
+			visible = append(visible, notification)
+		}
+	}
+	sortNotifications(visible, m.sortMode)
+	return visible
+}
+
+func passesAll(filters []listFilter, notification Notification) bool {
+	for _, filter := range filters {
+		if !filter.keep(notification) {
+			return false
+		}
+	}
+	return true
+}
+
+// filtered reports whether any filter is hiding notifications
+func (m Model) filtered() bool {
+	return len(m.activeFilters()) > 0
+}
+
+// filterStatus reports the active filters and how much they let through
+func (m Model) filterStatus() string {
+	filters := m.activeFilters()
+	if len(filters) == 0 {
+		return fmt.Sprintf("Showing all %d notifications", len(m.notifications))
+	}
+	active := make([]string, len(filters))
+	for i, filter := range filters {
+		active[i] = filter.status
+	}
+	return fmt.Sprintf("Filtered by %s (%d of %d)",
+		strings.Join(active, ", "),
+		len(m.visibleNotifications()),
+		len(m.notifications))
+}
+
+// filterLabel describes the active filters for the status line template
+func (m Model) filterLabel() string {
+	filters := m.activeFilters()
+	if len(filters) == 0 {
+		return "none"
+	}
+	active := make([]string, len(filters))
+	for i, filter := range filters {
+		active[i] = filter.label
+	}
+	return strings.Join(active, " ")
+}
+
+// clampSelection keeps selectedIndex within the visible list. Anything that
+// changes a filter calls it, or selectByID to follow a notification.
+func (m *Model) clampSelection() {
+	count := len(m.visibleNotifications())
+	if m.selectedIndex >= count {
+		m.selectedIndex = count - 1
+	}
+	if m.selectedIndex < 0 {
+		m.selectedIndex = 0
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// graphQLBatchSize is how many subjects are looked up per GraphQL query,
+// keeping each well inside GitHub's query cost limits
+const graphQLBatchSize = 50
+
+// graphQLSubjectFields selects what fetchDetails reads from the REST API
+const graphQLSubjectFields = `
+	__typename
+	... on Issue { state body author { login __typename } labels(first: 20) { nodes { name } } }
+	... on PullRequest { state body author { login __typename } labels(first: 20) { nodes { name } } }`
+
+// graphQLSubject is one resource(url:) result
+type graphQLSubject struct {
+	Typename string `json:"__typename"`
+	State    string `json:"state"`
+	Body     string `json:"body"`
+	Author   *struct {
+		Login    string `json:"login"`
+		Typename string `json:"__typename"` // matches REST's user type, e.g. Bot
+	} `json:"author"`
+	Labels *struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+}
+
+// fetchSubjectsGraphQL looks up the details of many issues and pull requests
+// in one query, rather than a REST request each. The notifications API has no
+// GraphQL equivalent, so subjects are addressed by their web URL. Subjects
+// missing from the result, e.g. because they were deleted, are left out of
+// the returned map; an error means the query as a whole failed.
+func fetchSubjectsGraphQL(notifications []Notification) (map[string]detailsLoadedMsg, error) {
+	var query strings.Builder
+	query.WriteString("query {")
+	aliases := make(map[string]string, len(notifications)) // alias -> subject URL
+	for i, notification := range notifications {
+		url, ok := apiToWebURL(notification.Subject.URL)
+		if !ok {
+			continue
+		}
+		alias := fmt.Sprintf("s%d", i)
+		aliases[alias] = notification.Subject.URL
+		fmt.Fprintf(&query, "\n%s: resource(url: %q) {%s\n}", alias, url, graphQLSubjectFields)
+	}
+	query.WriteString("\n}")
+	if len(aliases) == 0 {
+		return map[string]detailsLoadedMsg{}, nil
+	}
+
+	// gh reports an error when any lookup fails, e.g. for a deleted issue,
+	// while still returning the rest, so only a missing body is fatal
+	resp, err := ghAPI("graphql", "-f", "query="+query.String())
+	if limited := rateLimitFromResponse(resp); limited != nil {
+		return nil, limited
+	}
+	var result struct {
+		Data map[string]*graphQLSubject `json:"data"`
+	}
+	if jsonErr := json.Unmarshal(resp.body, &result); jsonErr != nil || result.Data == nil {
+		if err != nil {
+			return nil, fmt.Errorf("GraphQL query failed: %v", err)
+		}
+		return nil, fmt.Errorf("GraphQL query returned no data")
+	}
+
+	details := make(map[string]detailsLoadedMsg, len(result.Data))
+	for alias, subject := range result.Data {
+		if subject == nil {
+			continue
+		}
+		var d detailsLoadedMsg
+		d.body = subject.Body
+		d.state = strings.ToLower(subject.State)
+		if subject.Author != nil {
+			d.author = subject.Author.Login
+			d.authorType = subject.Author.Typename
+		}
+		if subject.Labels != nil {
+			for _, label := range subject.Labels.Nodes {
+				d.labels = append(d.labels, label.Name)
+			}
+		}
+		details[aliases[alias]] = d
+	}
+	return details, nil
+}
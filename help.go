@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// helpEntry describes one action in the help overlay
+type helpEntry struct {
+	action      string
+	description string
+}
+
+// helpSections groups every action for the help overlay. New actions should
+// be listed here, since this is the full reference; helpLine only shows the
+// most common keys.
+var helpSections = []struct {
+	title   string
+	entries []helpEntry
+}{
+	{"Navigation", []helpEntry{
+		{actionUp, "Move up"},
+		{actionDown, "Move down"},
+		{actionPageUp, "Page up"},
+		{actionPageDown, "Page down"},
+		{actionTop, "Go to top (gg)"},
+		{actionBottom, "Go to bottom"},
+	}},
+	{"Actions", []helpEntry{
+		{actionOpen, "Open in browser"},
+		{actionOpenReview, "Open a requested review's changes"},
+		{actionOpenSelected, "Open every selected notification"},
+		{actionOpenRepoList, "Open the repository's issues or pull requests"},
+		{actionCopyURL, "Copy the web URL"},
+		{actionCopyNumber, "Copy the number, e.g. #123"},
+		{actionCopySelectedURLs, "Copy the selection's web URLs, one per line"},
+		{actionMarkRead, "Mark as read, or the selection if any"},
+		{actionMarkReadKeep, "Mark as read but keep it in the list"},
+		{actionMarkDone, "Mark as done, or the selection if any"},
+		{actionUnsubscribe, "Unsubscribe from the thread"},
+		{actionSubscribe, "Subscribe to the thread again"},
+		{actionComment, "Comment on an issue or pull request"},
+		{actionSnooze, "Snooze, choosing until when"},
+		{actionMarkAllRead, "Mark all visible as read"},
+		{actionUndo, "Undo the last mark as read"},
+		{actionSelect, "Toggle selection"},
+		{actionInvertSelection, "Invert selection"},
+		{actionRefresh, "Refresh, or reload the open summary"},
+		{actionAutoRefresh, "Toggle auto-refresh"},
+		{actionQuit, "Quit"},
+	}},
+	{"View", []helpEntry{
+		{actionSearch, "Search titles and repositories"},
+		{actionSort, "Cycle sort order"},
+		{actionToggleDates, "Toggle relative/absolute dates"},
+		{actionWrap, "Toggle wrapping the selected title"},
+		{actionDensity, "Toggle compact/detailed rows"},
+		{actionFilterType, "Cycle type filter"},
+		{actionFilterReason, "Cycle reason filter"},
+		{actionFilterAge, "Cycle age filter"},
+		{actionRepoPicker, "Pick a repository to filter by"},
+		{actionHideBots, "Toggle hiding notifications from bots"},
+		{actionFocus, "Toggle focus: only unread mentions and review requests"},
+		{actionUnreadOnly, "Toggle showing unread only"},
+		{actionShowAll, "Toggle fetching read threads too"},
+		{actionClearFilters, "Clear all filters and the search"},
+		{actionSummary, "Show summary"},
+		{actionOverview, "Show inbox overview"},
+		{actionRawJSON, "Show the raw JSON, for debugging"},
+		{actionRepositories, "Show unread counts by repository"},
+		{actionHelp, "Show this help"},
+		{actionBack, "Close the current view"},
+	}},
+}
+
+// helpLines renders the help overlay's content, one line per entry
+func (m Model) helpLines() []string {
+	keys := m.config.keyMap
+	var lines []string
+	for i, section := range helpSections {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, headerStyle.Render(section.title))
+		for _, entry := range section.entries {
+			bound := keys.Labels(entry.action)
+			if bound == "" {
+				bound = "(unbound)"
+			}
+			lines = append(lines, fmt.Sprintf("  %-16s %s", bound, entry.description))
+		}
+	}
+	lines = append(lines, "",
+		dimStyle.Render(fmt.Sprintf("%s unread  %s read  %s new activity since you read it",
+			glyphs.unread, glyphs.read, glyphs.updated)),
+		dimStyle.Render("Type a row number then Enter to jump to it"),
+		dimStyle.Render("ctrl+c always quits"))
+	return lines
+}
+
+// helpViewHeight is how many help lines fit inside the overlay's border
+func (m Model) helpViewHeight() int {
+	height := m.terminalHeight - 7 // border, padding, title and scroll indicators
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+// maxHelpScroll is the furthest the help overlay can scroll
+func (m Model) maxHelpScroll() int {
+	maxScroll := len(m.helpLines()) - m.helpViewHeight()
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	return maxScroll
+}
+
+// helpView renders the help overlay centered on screen
+func (m Model) helpView() string {
+	lines := m.helpLines()
+	start := m.helpScroll
+	end := start + m.helpViewHeight()
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var content strings.Builder
+	content.WriteString(titleStyle.Render("Keybindings"))
+	content.WriteString("\n")
+	if start > 0 {
+		content.WriteString(dimStyle.Render(glyphs.scrollUp))
+	}
+	content.WriteString("\n")
+	content.WriteString(strings.Join(lines[start:end], "\n"))
+	content.WriteString("\n")
+	if end < len(lines) {
+		content.WriteString(dimStyle.Render(glyphs.scrollDown))
+	} else {
+		content.WriteString(dimStyle.Render("Press any key to close"))
+	}
+
+	return lipgloss.Place(m.terminalWidth, m.terminalHeight,
+		lipgloss.Center, lipgloss.Center,
+		summaryBoxStyle.Render(content.String()))
+}
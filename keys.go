@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Actions that keys can be bound to, named as they appear in the config
+const (
+	actionQuit             = "quit"
+	actionUp               = "up"
+	actionDown             = "down"
+	actionPageUp           = "page_up"
+	actionPageDown         = "page_down"
+	actionTop              = "top"
+	actionBottom           = "bottom"
+	actionOpen             = "open"
+	actionOpenReview       = "open_review"
+	actionOpenSelected     = "open_selected"
+	actionOpenRepoList     = "open_repo_list"
+	actionCopyURL          = "copy_url"
+	actionCopyNumber       = "copy_number"
+	actionCopySelectedURLs = "copy_selected_urls"
+	actionMarkRead         = "mark_read"
+	actionMarkReadKeep     = "mark_read_keep"
+	actionMarkDone         = "mark_done"
+	actionUnsubscribe      = "unsubscribe"
+	actionSubscribe        = "subscribe"
+	actionComment          = "comment"
+	actionSnooze           = "snooze"
+	actionMarkAllRead      = "mark_all_read"
+	actionUndo             = "undo"
+	actionSelect           = "select"
+	actionInvertSelection  = "invert_selection"
+	actionSearch           = "search"
+	actionSort             = "sort"
+	actionToggleDates      = "toggle_dates"
+	actionWrap             = "wrap"
+	actionDensity          = "density"
+	actionFilterType       = "filter_type"
+	actionFilterReason     = "filter_reason"
+	actionFilterAge        = "filter_age"
+	actionRepoPicker       = "filter_repo"
+	actionHideBots         = "hide_bots"
+	actionFocus            = "focus"
+	actionUnreadOnly       = "unread_only"
+	actionShowAll          = "show_all"
+	actionClearFilters     = "clear_filters"
+	actionRefresh          = "refresh"
+	actionAutoRefresh      = "auto_refresh"
+	actionSummary          = "summary"
+	actionOverview         = "overview"
+	actionRawJSON          = "raw_json"
+	actionRepositories     = "repositories"
+	actionHelp             = "help"
+	actionBack             = "back"
+)
+
+// defaultKeys binds every action. ctrl+c always quits and isn't listed here.
+var defaultKeys = map[string][]string{
+	actionQuit:             {"q"},
+	actionUp:               {"up", "k"},
+	actionDown:             {"down", "j"},
+	actionPageUp:           {"ctrl+u", "pgup"},
+	actionPageDown:         {"ctrl+d", "pgdown"},
+	actionTop:              {"g", "home"}, // a lone g waits for a second g
+	actionBottom:           {"G", "end"},
+	actionOpen:             {"enter"},
+	actionOpenReview:       {"V"},
+	actionOpenSelected:     {"O"},
+	actionOpenRepoList:     {"o"},
+	actionCopyURL:          {"y"},
+	actionCopyNumber:       {"Y"},
+	actionCopySelectedURLs: {"ctrl+y"},
+	actionMarkRead:         {"r"},
+	actionMarkReadKeep:     {"R"},
+	actionMarkDone:         {"d"},
+	actionUnsubscribe:      {"u"},
+	actionSubscribe:        {"S"},
+	actionComment:          {"C"},
+	actionSnooze:           {"z"},
+	actionMarkAllRead:      {"ctrl+a"},
+	actionUndo:             {"U"},
+	actionSelect:           {"x"},
+	actionInvertSelection:  {"*"},
+	actionSearch:           {"/"},
+	actionSort:             {"s"},
+	actionToggleDates:      {"T"},
+	actionWrap:             {"w"},
+	actionDensity:          {"v"},
+	actionFilterType:       {"t"},
+	actionFilterReason:     {"e"},
+	actionFilterAge:        {"A"},
+	actionRepoPicker:       {"p"},
+	actionHideBots:         {"b"},
+	actionFocus:            {"F"},
+	actionUnreadOnly:       {"n"},
+	actionShowAll:          {"N"},
+	actionClearFilters:     {"c"},
+	actionRefresh:          {"f", "F5"},
+	actionAutoRefresh:      {"a"},
+	actionSummary:          {"tab", " "},
+	actionOverview:         {"i"},
+	actionRawJSON:          {"J"},
+	actionRepositories:     {"P"},
+	actionHelp:             {"?"},
+	actionBack:             {"esc"},
+}
+
+// KeyMap resolves a key, as reported by tea.KeyMsg.String(), to its action
+type KeyMap struct {
+	actions map[string]string   // key -> action
+	keys    map[string][]string // action -> keys
+}
+
+// newKeyMap builds the key map from the defaults, with each action in
+// overrides replacing that action's default keys
+func newKeyMap(overrides map[string][]string) (KeyMap, error) {
+	keys := make(map[string][]string, len(defaultKeys))
+	for action, bound := range defaultKeys {
+		keys[action] = bound
+	}
+	for action, bound := range overrides {
+		if _, ok := defaultKeys[action]; !ok {
+			return KeyMap{}, fmt.Errorf("unknown action %q in keys", action)
+		}
+		normalized := make([]string, len(bound))
+		for i, key := range bound {
+			// Bubble Tea names the space bar " ", which is easy to get wrong
+			// in YAML
+			if key == "space" {
+				key = " "
+			}
+			normalized[i] = key
+		}
+		keys[action] = normalized
+	}
+
+	// Visit actions in a fixed order so conflicts are reported consistently
+	names := make([]string, 0, len(keys))
+	for action := range keys {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+
+	actions := make(map[string]string)
+	for _, action := range names {
+		for _, key := range keys[action] {
+			if key == "ctrl+c" {
+				return KeyMap{}, fmt.Errorf("ctrl+c is reserved for quitting and can't be bound to %q", action)
+			}
+			if other, ok := actions[key]; ok {
+				return KeyMap{}, fmt.Errorf("key %q is bound to both %q and %q", key, other, action)
+			}
+			actions[key] = action
+		}
+	}
+
+	return KeyMap{actions: actions, keys: keys}, nil
+}
+
+// Action returns the action bound to key, or "" if there is none
+func (k KeyMap) Action(key string) string {
+	return k.actions[key]
+}
+
+// Help describes the first key bound to action for the help line, e.g.
+// "r:Mark Read". It returns "" if the action has no keys.
+func (k KeyMap) Help(action, label string) string {
+	bound := k.keys[action]
+	if len(bound) == 0 {
+		return ""
+	}
+	return keyLabel(bound[0]) + ":" + label
+}
+
+// HelpAny describes the first key bound to each of actions under one label,
+// e.g. "i/Esc:Back" for several ways of doing the same thing. Unbound
+// actions are left out.
+func (k KeyMap) HelpAny(label string, actions ...string) string {
+	var labels []string
+	for _, action := range actions {
+		if bound := k.keys[action]; len(bound) > 0 {
+			labels = append(labels, keyLabel(bound[0]))
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return strings.Join(labels, "/") + ":" + label
+}
+
+// Labels lists every key bound to action, e.g. "f/F5", or "" if there are none
+func (k KeyMap) Labels(action string) string {
+	labels := make([]string, len(k.keys[action]))
+	for i, key := range k.keys[action] {
+		labels[i] = keyLabel(key)
+	}
+	return strings.Join(labels, "/")
+}
+
+// keyLabel formats a key name for display
+func keyLabel(key string) string {
+	switch key {
+	case " ":
+		return "Space"
+	case "enter", "tab", "esc", "home", "end":
+		return strings.ToUpper(key[:1]) + key[1:]
+	}
+	if strings.HasPrefix(key, "ctrl+") && len(key) == len("ctrl+")+1 {
+		return "^" + strings.ToUpper(key[len("ctrl+"):])
+	}
+	return key
+}
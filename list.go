@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// runList prints notifications for scripting, without starting the TUI
+func runList(w io.Writer, client GitHubClient, config Config, query notificationsQuery, asJSON bool, unreadOnly bool) error {
+	fetched, warning, err := loadNotifications(client, config.PostFetchCmd, query, "", nil)
+	if err != nil {
+		return err
+	}
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	filter := config.DefaultFilter
+	if unreadOnly {
+		filter.UnreadOnly = true
+	}
+	notifications := make([]Notification, 0, len(fetched.notifications))
+	for _, notification := range fetched.notifications {
+		if filter.keep(notification) {
+			notifications = append(notifications, notification)
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(notifications)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "UNREAD\tUPDATED\tREPOSITORY\tTYPE\tREASON\tTITLE")
+	for _, notification := range notifications {
+		unread := ""
+		if notification.Unread {
+			unread = "*"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			unread,
+			notification.FormattedDate(),
+			notification.RepoName(),
+			notification.TypeDisplay(),
+			notification.Reason,
+			notification.Subject.Title)
+	}
+	return table.Flush()
+}
@@ -1,17 +1,34 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/gen2brain/beeep"
+	"github.com/muesli/termenv"
 )
 
 // Data Models
@@ -22,6 +39,7 @@ type Notification struct {
 	Repository Repository `json:"repository"`
 	Subject    Subject    `json:"subject"`
 	UpdatedAt  time.Time  `json:"updated_at"`
+	LastReadAt *time.Time `json:"last_read_at"` // nil if never read
 }
 
 type Repository struct {
@@ -36,10 +54,19 @@ type Subject struct {
 
 // Helper methods for display
 func (n *Notification) StatusIcon() string {
+	if n.UpdatedSinceRead() {
+		return glyphs.updated
+	}
 	if n.Unread {
-		return "●" // Filled circle for unread
+		return glyphs.unread
 	}
-	return "○" // Empty circle for read
+	return glyphs.read
+}
+
+// UpdatedSinceRead reports whether a thread that was read before has new
+// activity, as opposed to one that has never been read
+func (n *Notification) UpdatedSinceRead() bool {
+	return n.Unread && n.LastReadAt != nil && n.UpdatedAt.After(*n.LastReadAt)
 }
 
 func (n *Notification) TypeDisplay() string {
@@ -57,75 +84,370 @@ func (n *Notification) TypeDisplay() string {
 	}
 }
 
+// ReasonDisplay returns a short human label for why the notification was sent
+func (n *Notification) ReasonDisplay() string {
+	return reasonLabel(n.Reason)
+}
+
 func (n *Notification) FormattedDate() string {
 	return n.UpdatedAt.Format("01-02 15:04")
 }
 
+// RelativeDate returns how long ago the notification was updated, e.g. "3h ago"
+func (n *Notification) RelativeDate() string {
+	return relativeDate(n.UpdatedAt, time.Now())
+}
+
+func relativeDate(t, now time.Time) string {
+	elapsed := now.Sub(t)
+	switch {
+	case elapsed < time.Minute:
+		// Includes timestamps slightly in the future from clock skew
+		return "just now"
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed.Hours()))
+	case elapsed < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(elapsed.Hours()/24))
+	case elapsed < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(elapsed.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%dy ago", int(elapsed.Hours()/24/365))
+	}
+}
+
 func (n *Notification) RepoName() string {
 	return n.Repository.FullName
 }
 
+// reasonOrder is the order the reason filter cycles through. Reasons not
+// listed here are cycled after these, alphabetically.
+var reasonOrder = []string{
+	"review_requested",
+	"assign",
+	"mention",
+	"team_mention",
+	"author",
+	"comment",
+	"state_change",
+	"ci_activity",
+	"subscribed",
+	"manual",
+	"invitation",
+	"security_alert",
+}
+
+func reasonLabel(reason string) string {
+	switch reason {
+	case "review_requested":
+		return "review requested"
+	case "team_mention":
+		return "team mention"
+	case "state_change":
+		return "state change"
+	case "ci_activity":
+		return "ci activity"
+	case "security_alert":
+		return "security alert"
+	default:
+		return reason
+	}
+}
+
+// reasonAbbrev shortens a reason to fit the list's 10-character column
+func reasonAbbrev(reason string) string {
+	switch reason {
+	case "review_requested":
+		return "review"
+	case "approval_requested":
+		return "approval"
+	case "team_mention":
+		return "team"
+	case "state_change":
+		return "state"
+	case "ci_activity":
+		return "ci"
+	case "security_alert":
+		return "security"
+	}
+	if len(reason) > 10 {
+		return reason[:10]
+	}
+	return reason
+}
+
 // Bubble Tea Model
 type Model struct {
-	notifications  []Notification
-	selectedIndex  int
-	loading        bool
-	err            error
-	showingSummary bool
-	summaryLoading bool
-	summaryHeader  string
-	summaryBody    string
-	summaryCache   map[string]detailsLoadedMsg
-	summaryScroll  int
-	summaryLines   []string
-	statusMessage  string
-	terminalWidth  int
-	terminalHeight int
+	config        Config
+	client        GitHubClient
+	notifications []Notification
+	selectedIndex int    // index into visibleNotifications()
+	pendingG      bool   // first g of gg was pressed
+	jumpBuffer    string // row number typed so far, jumped to on enter
+	typeFilter    string // a TypeDisplay value, or empty for all
+	reasonFilter  string
+	unreadOnly    bool               // hide read notifications
+	repoFilter    string             // a RepoName value, or empty for all
+	ageFilter     string             // one of ageFilters, or empty for all
+	hideBots      bool               // hide notifications whose subject a bot opened
+	focusMode     bool               // only unread mentions and review requests
+	preFocus      filterState        // the filters focus mode set aside
+	showAll       bool               // fetch read threads too, such as unsubscribed ones
+	repo          string             // --repo: only fetch this repository's notifications
+	listedQuery   notificationsQuery // what the list was fetched with
+	etag          string             // of the list's first page
+	authExpired   bool               // a gh call failed authentication since the last fetch
+	dryRun        bool               // pretend to mark, unsubscribe and comment without calling GitHub
+	viewer        string             // the authenticated user's login, once known
+	searchQuery   string             // case-insensitive substring of title or repo
+	searchMode    bool               // typing into the search prompt
+	commenting    bool               // typing a comment on commentTarget
+	commentInput  textarea.Model
+	commentTarget Notification
+	sortMode      string               // one of sortModes
+	absoluteDates bool                 // show dates as "01-02 15:04" rather than "3h ago"
+	wrapSelected  bool                 // show the selected row's full title, wrapped
+	density       string               // densityCompact or densityDetailed
+	selected      map[string]bool      // multi-select, keyed by notification ID
+	snoozed       map[string]time.Time // hidden until the wake time, by ID
+
+	// Yes/no prompt; onConfirm runs if the user answers yes, or presses
+	// quit again when confirmingQuit
+	confirming     bool
+	confirmPrompt  string
+	onConfirm      tea.Cmd
+	confirmingQuit bool
+
+	inFlight int // single mark and unsubscribe requests awaiting a reply
+
+	loading           bool
+	cached            bool // notifications came from the cache, not a fetch
+	err               error
+	showingOverview   bool           // aggregate counts across the inbox
+	overviewViewport  viewport.Model // scrolls the overview's counts
+	showingHelp       bool           // full keybinding reference
+	showingRepoPicker bool
+	showingSnooze     bool // choosing how long to snooze snoozeTarget
+	snoozeIndex       int
+	snoozeTarget      Notification
+	repoPickerIndex   int
+	showingRepos      bool // unread and total counts per repository
+	reposIndex        int
+	helpScroll        int
+	showingSummary    bool
+	summaryLoading    bool
+	summaryHeader     string
+	summaryBody       string
+	summaryPlain      bool                        // the body is shown as is rather than as markdown
+	detailCache       map[string]detailsLoadedMsg // by Subject.URL
+	subjectStates     map[string]subjectState     // keyed by subject URL
+	summaryViewport   viewport.Model              // scrolls the summary pane's rendered lines
+	statusMessage     string
+	lastRefresh       time.Time
+	fetching          bool // a fetch is in flight, manual or automatic
+	page              int  // pages loaded so far, with infinite scroll
+	hasMore           bool // the server has pages beyond page
+	pageLoading       bool // a later page is being fetched
+	pageGen           int  // invalidates page loads started before a full fetch
+	spinner           spinner.Model
+	rateLimitReset    time.Time
+	rateLimit         rateLimitInfo // last known quota; zero until fetched
+	terminalWidth     int
+	terminalHeight    int
+
+	// Auto-refresh; refreshGen invalidates ticks from an earlier toggle so
+	// only one tick loop is ever live
+	autoRefresh     bool
+	refreshInterval time.Duration
+	refreshGen      int
+	pollInterval    time.Duration // GitHub's X-Poll-Interval, the least it allows
+
+	// Bulk mark progress, for mark-all-read and actions on the selection;
+	// IDs are sent in batches of bulkMarkBatchSize
+	bulkMarkDone bool // marking as done rather than read
+	bulkPending  []string
+	bulkTotal    int
+	bulkDone     int
+	bulkFailed   int
+
+	// Recently marked-read notifications, most recent last, for undo
+	undoStack []undoEntry
+
+	// Triage stats for the session, reported on quit
+	sessionStart time.Time
+	readCount    int
+	doneCount    int
+	openedCount  int
+}
+
+// undoEntry remembers a notification marked read and where it was in the list
+type undoEntry struct {
+	notification Notification
+	index        int
+}
+
+// subjectState is the open/closed/merged state of an issue or pull request,
+// as of the notification update it was fetched for. An empty state means it
+// couldn't be determined.
+type subjectState struct {
+	state      string
+	author     string
+	authorType string // "Bot" for app accounts
+	asOf       time.Time
 }
 
+// maxUndo bounds the undo stack
+const maxUndo = 20
+
 // Messages
-type notificationsLoadedMsg []Notification
-type notificationMarkedMsg string
+type notificationsLoadedMsg struct {
+	notifications []Notification
+	warning       string
+	hasMore       bool // only the first page was fetched
+	query         notificationsQuery
+	etag          string
+	pollInterval  time.Duration
+}
+type notificationsUnchangedMsg struct{} // a conditional fetch got a 304
+type pageLoadedMsg struct {
+	gen           int // the pageGen that requested it
+	page          int
+	notifications []Notification
+	hasMore       bool
+	warning       string
+	err           error
+}
+type notificationMarkedMsg struct {
+	id   string
+	keep bool // leave it in the list, shown as read
+}
+type notificationDoneMsg string
+type notificationOpenedMsg string
+type unsubscribedMsg string
+type subscribedMsg string
+type bulkMarkStartMsg struct {
+	ids  []string
+	done bool // mark as done rather than read
+}
+type bulkMarkedMsg struct {
+	marked     []string
+	failed     int
+	authFailed bool // some failed because the gh login has expired
+}
 type detailsLoadedMsg struct {
-	body   string
-	author string
+	body       string
+	author     string
+	authorType string // User, Bot or Organization
+	state      string // open, closed or merged, where the subject has one
+	labels     []string
+}
+type subjectDetailMsg struct {
+	notification Notification
+	details      detailsLoadedMsg
+	ok           bool                    // details were fetched
+	next         <-chan subjectDetailMsg // the rest of the stream
 }
+type selectedOpenedMsg struct {
+	opened int
+	failed int
+}
+
+// actionFailedMsg reports a failed mark or unsubscribe request. It's a struct
+// because an error type would also match errorMsg in Update's type switch.
+type actionFailedMsg struct {
+	err error
+}
+type viewerMsg string // the authenticated user's login
+type authErrorMsg struct {
+	err     error
+	pending bool // from a mark, unsubscribe or comment counted in inFlight
+}
+type rateLimitedMsg time.Time // when the limit resets
+type rateLimitInfoMsg rateLimitInfo
+type fetchProgressMsg struct {
+	fetched int          // notifications so far
+	next    chan tea.Msg // the rest of the fetch's updates
+}
+type fetchRetryMsg int      // 1 for the first retry
+type autoRefreshTickMsg int // the refreshGen that scheduled it
 type errorMsg error
 type statusMsg string
 
-// Styles
-var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#04B575"))
-
-	headerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FAFAFA")).
-			Background(lipgloss.Color("#7D56F4")).
-			Padding(0, 1)
+// Glyphs, with ASCII fallbacks for terminals that can't show unicode
+type glyphSet struct {
+	unread     string
+	read       string
+	updated    string
+	marker     string
+	important  string
+	scrollUp   string
+	scrollDown string
+	arrows     string
+	dash       string
+	spinner    spinner.Spinner
+}
 
-	selectedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#BD93F9")).
-			Bold(true)
+var (
+	unicodeGlyphs = glyphSet{
+		unread:     "●", // Filled circle for unread
+		read:       "○", // Empty circle for read
+		updated:    "◉", // Unread again after being read
+		marker:     "✓",
+		important:  "⚑",
+		scrollUp:   "▲",
+		scrollDown: "▼",
+		arrows:     "↑↓",
+		dash:       "—",
+		spinner:    spinner.Dot,
+	}
 
-	unreadStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF5F87"))
+	asciiGlyphs = glyphSet{
+		unread:     "*",
+		read:       "o",
+		updated:    "+",
+		marker:     "x",
+		important:  "!",
+		scrollUp:   "^",
+		scrollDown: "v",
+		arrows:     "j/k",
+		dash:       "-",
+		spinner:    spinner.Line,
+	}
 
-	readStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#50FA7B"))
+	glyphs = unicodeGlyphs
+)
 
-	dimStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#6272A4"))
+// supportsUnicode guesses from the environment whether the terminal can
+// display unicode. Without any locale set we assume it can.
+func supportsUnicode() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value = strings.ToUpper(value)
+			return strings.Contains(value, "UTF-8") || strings.Contains(value, "UTF8")
+		}
+	}
+	return true
+}
 
-	statusStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#8BE9FD"))
+// usePlainRendering swaps unicode glyphs and borders for ASCII. Colors need
+// no handling here: lipgloss already drops them for NO_COLOR, --no-color,
+// dumb terminals and non-TTY output, leaving the padded text aligned.
+func usePlainRendering() {
+	glyphs = asciiGlyphs
+	summaryBoxStyle = summaryBoxStyle.Border(lipgloss.ASCIIBorder())
+}
 
-	summaryBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#FF79C6")).
-			Padding(1, 2)
-)
+// renderReason returns the reason label, colored if the reason has a style
+func renderReason(reason string) string {
+	if style, ok := reasonStyles[reason]; ok {
+		return style.Render(reasonLabel(reason))
+	}
+	return reasonLabel(reason)
+}
 
 // GitHub CLI functions
 func checkGitHubCLI() error {
@@ -135,519 +457,3033 @@ func checkGitHubCLI() error {
 	}
 
 	// Check if authenticated
-	cmd := exec.Command("gh", "auth", "status")
-	if err := cmd.Run(); err != nil {
+	if _, err := ghOutput("auth", "status"); err != nil {
+		if ghHost != defaultHost {
+			return fmt.Errorf("not authenticated with %s. Run: gh auth login --hostname %s", ghHost, ghHost)
+		}
 		return fmt.Errorf("not authenticated with GitHub. Run: gh auth login")
 	}
 
 	return nil
 }
 
-func fetchNotifications() ([]Notification, error) {
-	cmd := exec.Command("gh", "api", "notifications", "--paginate")
-	output, err := cmd.Output()
+// notificationsPerPage is the most the notifications endpoint returns at once
+const notificationsPerPage = 50
+
+// notificationsPage is one page of notifications, or with fetchNotifications
+// the whole inbox
+type notificationsPage struct {
+	notifications []Notification
+	hasMore       bool          // there are pages after this one
+	etag          string        // of the first page, to make the next fetch conditional
+	pollInterval  time.Duration // the least time GitHub asks between polls
+}
+
+// errNotModified is returned for a conditional fetch when nothing changed
+var errNotModified = errors.New("notifications not modified")
+
+// fetchNotifications pages through the inbox itself, rather than with
+// --paginate, so each page's headers can be checked for rate limiting.
+// progress, if not nil, is called with the running total after each page
+// that has more to follow. Only the first page is fetched conditionally on
+// etag, since any change to the inbox changes it.
+func fetchNotifications(client GitHubClient, query notificationsQuery, etag string, progress func(fetched int)) (notificationsPage, error) {
+	var inbox notificationsPage
+	for page := 1; ; page++ {
+		if page > 1 {
+			etag = ""
+		}
+		batch, err := client.NotificationsPage(page, query, etag)
+		if err != nil {
+			return notificationsPage{}, err
+		}
+		if page == 1 {
+			inbox.pollInterval = batch.pollInterval
+			// The etag only covers page 1, so a 304 for it says nothing
+			// about the pages after
+			if !batch.hasMore {
+				inbox.etag = batch.etag
+			}
+		}
+		inbox.notifications = append(inbox.notifications, batch.notifications...)
+		if !batch.hasMore {
+			return inbox, nil
+		}
+		if progress != nil {
+			progress(len(inbox.notifications))
+		}
+	}
+}
+
+// notificationsQuery narrows or widens what the notifications endpoint
+// returns. The zero value is the inbox: unread threads you're subscribed to.
+type notificationsQuery struct {
+	all           bool   // include read threads, such as ones unsubscribed from
+	participating bool   // only threads you're directly involved in
+	repo          string // only this repository's, as owner/name
+}
+
+// repoNamePattern matches owner/name, with GitHub's allowed characters
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]+/[A-Za-z0-9._-]+$`)
+
+// validateRepoName checks a --repo value before it's put in a URL
+func validateRepoName(name string) error {
+	if !repoNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid --repo %q, expected owner/name, e.g. cli/cli", name)
+	}
+	return nil
+}
+
+// fetchNotificationsPage fetches one page of the inbox, counting from 1. With
+// an etag, GitHub answers 304 if nothing changed, which doesn't count
+// against the rate limit.
+func fetchNotificationsPage(page int, query notificationsQuery, etag string) (notificationsPage, error) {
+	endpoint := "notifications"
+	if query.repo != "" {
+		// Far less to page through than filtering the whole inbox
+		endpoint = "repos/" + query.repo + "/notifications"
+	}
+	args := []string{fmt.Sprintf("%s?per_page=%d&page=%d&all=%t&participating=%t",
+		endpoint, notificationsPerPage, page, query.all, query.participating)}
+	if etag != "" {
+		args = append(args, "-H", "If-None-Match: "+etag)
+	}
+	resp, err := ghAPI(args...)
+	if limited := rateLimitFromResponse(resp); limited != nil {
+		return notificationsPage{}, limited
+	}
+	// gh treats anything but a 2xx as failure
+	if resp.status == http.StatusNotModified {
+		return notificationsPage{}, errNotModified
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch notifications: %v", err)
+		if isAuthError(err) {
+			return notificationsPage{}, err
+		}
+		// gh explains network failures on stderr, while HTTP errors come
+		// back as a body
+		if detail := ghErrorDetail(err); detail != "" {
+			return notificationsPage{}, fmt.Errorf("failed to fetch notifications: %s", detail)
+		}
+		if resp.status != 0 {
+			return notificationsPage{}, fmt.Errorf("failed to fetch notifications: HTTP %d: %s", resp.status, firstLine(resp.body))
+		}
+		return notificationsPage{}, fmt.Errorf("failed to fetch notifications: %v", err)
 	}
 
-	var notifications []Notification
-	if err := json.Unmarshal(output, &notifications); err != nil {
-		return nil, fmt.Errorf("failed to parse notifications: %v", err)
+	batch := notificationsPage{hasMore: hasNextPage(resp.header), etag: resp.header.Get("ETag")}
+	if seconds, err := strconv.Atoi(resp.header.Get("X-Poll-Interval")); err == nil && seconds > 0 {
+		batch.pollInterval = time.Duration(seconds) * time.Second
+	}
+
+	// A quiet inbox can come back with no body at all rather than []
+	if len(bytes.TrimSpace(resp.body)) == 0 {
+		return batch, nil
 	}
 
-	return notifications, nil
+	if err := json.Unmarshal(resp.body, &batch.notifications); err != nil {
+		// e.g. an HTML error page from a proxy, or a truncated response
+		log.Printf("failed to parse notifications page %d: %v", page, err)
+		return notificationsPage{}, fmt.Errorf("unexpected response from GitHub (HTTP %d): %s", resp.status, firstLine(resp.body))
+	}
+	return batch, nil
 }
 
 func markAsRead(id string) error {
-	cmd := exec.Command("gh", "api",
+	_, err := ghOutput("api",
 		"--method", "PATCH",
 		"-H", "Accept: application/vnd.github+json",
 		"-H", "X-GitHub-Api-Version: 2022-11-28",
 		fmt.Sprintf("/notifications/threads/%s", id))
+	if err != nil {
+		// A thread read or marked done elsewhere since the last fetch is
+		// already in the state we want, so treat it as success
+		if threadGone(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// markAsDone removes the thread from the inbox. Unlike read threads, done
+// threads don't resurface on new activity.
+func markAsDone(id string) error {
+	_, err := ghOutput("api",
+		"--method", "DELETE",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: 2022-11-28",
+		fmt.Sprintf("/notifications/threads/%s", id))
+	if err != nil {
+		if threadGone(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
 
-	return cmd.Run()
+// unsubscribe ignores all future notifications for the thread and marks it
+// read
+func unsubscribe(id string) error {
+	_, err := ghOutput("api",
+		"--method", "PUT",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: 2022-11-28",
+		fmt.Sprintf("/notifications/threads/%s/subscription", id),
+		"-F", "ignored=true")
+	if err != nil {
+		return err
+	}
+	return markAsRead(id)
 }
 
-func extractIssueNumber(url string) string {
-	parts := strings.Split(url, "/")
-	if len(parts) > 0 {
-		return parts[len(parts)-1]
+// subscribe undoes unsubscribe, so new activity on the thread notifies again
+func subscribe(id string) error {
+	_, err := ghOutput("api",
+		"--method", "PUT",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: 2022-11-28",
+		fmt.Sprintf("/notifications/threads/%s/subscription", id),
+		"-F", "ignored=false")
+	return err
+}
+
+// threadGone reports whether a gh api error means the thread no longer
+// exists in the inbox
+func threadGone(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
 	}
-	return ""
+	stderr := string(exitErr.Stderr)
+	return strings.Contains(stderr, "HTTP 404") || strings.Contains(stderr, "HTTP 410")
+}
+
+// extractIssueNumber returns the last path segment of a subject URL, which
+// for issues and pull requests is the number
+func extractIssueNumber(url string) string {
+	parts := strings.Split(strings.TrimSuffix(url, "/"), "/")
+	return parts[len(parts)-1]
 }
 
 func openInBrowser(notification Notification) error {
 	repo := notification.RepoName()
 	issueNum := extractIssueNumber(notification.Subject.URL)
 
-	var cmd *exec.Cmd
+	// other types, and anything without a page of its own, open the
+	// repository
+	args := []string{"repo", "view", repo, "--web"}
 
 	if issueNum != "" {
 		switch notification.Subject.Type {
 		case "Issue":
-			cmd = exec.Command("gh", "issue", "view", issueNum, "-R", repo, "--web")
+			args = []string{"issue", "view", issueNum, "-R", repo, "--web"}
 		case "PullRequest":
-			cmd = exec.Command("gh", "pr", "view", issueNum, "-R", repo, "--web")
-		// discussions
-		case "Discussion":
-			// rewrite url to allow opening in browser
-			notification.Subject.URL = strings.Replace(notification.Subject.URL, "https://api.github.com", "https://github.com", 1)
-			notification.Subject.URL = strings.Replace(notification.Subject.URL, "/repos/", "/", 1)
-			cmd = exec.Command("open", notification.Subject.URL)
-		// releases
-		case "Release":
-			cmd = exec.Command("gh", "release", "view", issueNum, "-R", repo, "--web")
-		// other types
-		default:
-			cmd = exec.Command("gh", "repo", "view", repo, "--web")
+			args = []string{"pr", "view", issueNum, "-R", repo, "--web"}
+		// discussions and releases have no gh subcommand taking their API ID,
+		// so open their web page directly
+		case "Discussion", "Release":
+			if url, ok := subjectWebURL(notification); ok {
+				cmd := openURLCommand(url)
+				err := cmd.Run()
+				logCommand(cmd, err)
+				return err
+			}
 		}
-	} else {
-		cmd = exec.Command("gh", "repo", "view", repo, "--web")
 	}
 
-	return cmd.Run()
+	_, err := ghOutput(args...)
+	return err
+}
+
+// isReviewRequest reports whether a notification asks us to review a pull
+// request, whose "Files changed" tab can be opened directly
+func isReviewRequest(notification Notification) bool {
+	return notification.Subject.Type == "PullRequest" && notification.Reason == "review_requested" &&
+		extractIssueNumber(notification.Subject.URL) != ""
+}
+
+// openReviewInBrowser opens the "Files changed" tab of a pull request
+func openReviewInBrowser(notification Notification) error {
+	issueNum := extractIssueNumber(notification.Subject.URL)
+	url := webURL(fmt.Sprintf("%s/pull/%s/files", notification.RepoName(), issueNum))
+	cmd := openURLCommand(url)
+	err := cmd.Run()
+	logCommand(cmd, err)
+	return err
+}
+
+// repoListArgs are the gh arguments opening the repository's list of issues
+// or pull requests, whichever the notification is, or the repository itself
+// for other subjects
+func repoListArgs(notification Notification) []string {
+	repo := notification.RepoName()
+	switch notification.Subject.Type {
+	case "Issue":
+		return []string{"issue", "list", "-R", repo, "--web"}
+	case "PullRequest":
+		return []string{"pr", "list", "-R", repo, "--web"}
+	}
+	return []string{"repo", "view", repo, "--web"}
+}
+
+func openRepoListInBrowser(notification Notification) error {
+	_, err := ghOutput(repoListArgs(notification)...)
+	return err
+}
+
+// apiToWebURL translates a subject's REST URL, e.g.
+// https://api.github.com/repos/owner/repo/pulls/1, to its page on the web.
+// It reports false for URLs it doesn't recognise.
+func apiToWebURL(apiURL string) (string, bool) {
+	prefix := apiReposPrefix()
+	if !strings.HasPrefix(apiURL, prefix) {
+		return "", false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(apiURL, prefix), "/"), "/")
+	if len(parts) != 4 || parts[3] == "" {
+		return "", false
+	}
+	owner, repo, kind, id := parts[0], parts[1], parts[2], parts[3]
+
+	switch kind {
+	case "pulls":
+		kind = "pull"
+	case "commits":
+		kind = "commit"
+	case "issues", "discussions":
+	default:
+		return "", false
+	}
+	return webURL(fmt.Sprintf("%s/%s/%s/%s", owner, repo, kind, id)), true
+}
+
+// subjectWebURL finds the web page for a notification's subject. Releases
+// are addressed by ID in the API but by tag on the web, so their page is
+// looked up rather than translated.
+func subjectWebURL(notification Notification) (string, bool) {
+	if notification.Subject.Type != "Release" {
+		return apiToWebURL(notification.Subject.URL)
+	}
+	if notification.Subject.URL == "" {
+		return "", false
+	}
+
+	output, err := ghOutput("api", notification.Subject.URL, "--jq", ".html_url")
+	if err != nil {
+		return "", false
+	}
+	url := strings.TrimSpace(string(output))
+	return url, url != ""
+}
+
+// notificationWebURL is the page openInBrowser would show, falling back to the
+// repository for subjects without their own page
+func notificationWebURL(client GitHubClient, notification Notification) string {
+	if url, ok := client.SubjectWebURL(notification); ok {
+		return url
+	}
+	return webURL(notification.RepoName())
+}
+
+// openURLCommand opens a URL with the platform's default handler
+func openURLCommand(url string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		return exec.Command("xdg-open", url)
+	}
 }
 
 // Bubble Tea Commands
-func fetchNotificationsCmd() tea.Cmd {
+// query is what to fetch: the inbox, or with N all threads, narrowed to
+// participating ones if configured
+func (m Model) query() notificationsQuery {
+	return notificationsQuery{all: m.showAll, participating: m.config.Participating, repo: m.repo}
+}
+
+func fetchNotificationsCmd(client GitHubClient, config Config, query notificationsQuery) tea.Cmd {
+	return fetchAttemptCmd(client, config, query, "", 0)
+}
+
+// refreshETag is the etag auto-refresh sends, so a quiet inbox doesn't use
+// up the rate limit. It only describes the list shown if that was fetched
+// with the same query, and only while the list fits on the one page it
+// covers.
+func (m Model) refreshETag() string {
+	if m.cached || m.query() != m.listedQuery || m.hasMore || m.page > 1 {
+		return ""
+	}
+	return m.etag
+}
+
+// maxFetchRetries is how many times a failed fetch is retried, waiting
+// fetchRetryDelay between attempts, before the error is shown
+const maxFetchRetries = 3
+
+// fetchRetryDelay backs off exponentially: 1s, 2s, 4s
+func fetchRetryDelay(retry int) time.Duration {
+	return time.Second << (retry - 1)
+}
+
+// fetchAttemptCmd fetches notifications, or just the first page with
+// infinite scroll; retry counts the attempts so far after the first. A
+// fetchProgressMsg is streamed after each page, ahead of the result. Given
+// an etag, an unchanged inbox gives notificationsUnchangedMsg instead.
+func fetchAttemptCmd(client GitHubClient, config Config, query notificationsQuery, etag string, retry int) tea.Cmd {
 	return func() tea.Msg {
-		notifications, err := fetchNotifications()
-		if err != nil {
-			return errorMsg(err)
+		updates := make(chan tea.Msg)
+		go func() {
+			updates <- fetchAttempt(client, config, query, etag, retry, func(fetched int) {
+				updates <- fetchProgressMsg{fetched: fetched}
+			})
+		}()
+		return waitForFetchCmd(updates)()
+	}
+}
+
+// fetchAttempt is the body of fetchAttemptCmd, reporting each page to progress
+func fetchAttempt(client GitHubClient, config Config, query notificationsQuery, etag string, retry int, progress func(fetched int)) tea.Msg {
+	var fetched notificationsPage
+	var warning string
+	var err error
+	if config.InfiniteScroll {
+		fetched, warning, err = loadNotificationsPage(client, config.PostFetchCmd, query, etag, 1)
+	} else {
+		fetched, warning, err = loadNotifications(client, config.PostFetchCmd, query, etag, progress)
+	}
+	if errors.Is(err, errNotModified) {
+		return notificationsUnchangedMsg{}
+	}
+	var limited rateLimitError
+	if errors.As(err, &limited) {
+		// Retrying would only burn more of the quota
+		return rateLimitedMsg(limited.reset)
+	}
+	if isAuthError(err) {
+		// Retrying can't help until the user logs in again
+		return authErrorMsg{err: err}
+	}
+	if err != nil {
+		if retry < maxFetchRetries {
+			return fetchRetryMsg(retry + 1)
 		}
-		return notificationsLoadedMsg(notifications)
+		return errorMsg(err)
+	}
+	// Best effort; a stale or missing cache only costs the next startup,
+	// which shows the whole inbox rather than all threads or one repository
+	if !query.all && query.repo == "" {
+		_ = saveCache(fetched.notifications)
+	}
+	return notificationsLoadedMsg{
+		notifications: fetched.notifications,
+		warning:       warning,
+		hasMore:       fetched.hasMore,
+		query:         query,
+		etag:          fetched.etag,
+		pollInterval:  fetched.pollInterval,
 	}
 }
 
-func markAsReadCmd(id string) tea.Cmd {
+// waitForFetchCmd delivers the next update from a fetch in progress; only a
+// fetchProgressMsg has more to follow
+func waitForFetchCmd(updates chan tea.Msg) tea.Cmd {
 	return func() tea.Msg {
-		err := markAsRead(id)
-		if err != nil {
-			return errorMsg(fmt.Errorf("failed to mark as read: %v", err))
+		msg := <-updates
+		if progress, ok := msg.(fetchProgressMsg); ok {
+			progress.next = updates
+			return progress
 		}
-		return notificationMarkedMsg(id)
+		return msg
 	}
 }
 
-func openInBrowserCmd(notification Notification) tea.Cmd {
+// fetchPageCmd fetches a later page for infinite scroll
+func fetchPageCmd(client GitHubClient, postFetchCmd string, query notificationsQuery, page, gen int) tea.Cmd {
 	return func() tea.Msg {
-		err := openInBrowser(notification)
-		if err != nil {
-			return errorMsg(fmt.Errorf("failed to open in browser: %v", err))
+		fetched, warning, err := loadNotificationsPage(client, postFetchCmd, query, "", page)
+		return pageLoadedMsg{
+			gen:           gen,
+			page:          page,
+			notifications: fetched.notifications,
+			hasMore:       fetched.hasMore,
+			warning:       warning,
+			err:           err,
 		}
-		return statusMsg("Opened in browser")
 	}
 }
 
-func fetchDetails(url string, notificationType string) (string, string, error) {
-	cmd := exec.Command("gh", "api", url)
-	output, err := cmd.Output()
+// loadNotifications fetches notifications and runs them through the
+// post_fetch_cmd hook, if any. A failing hook is reported as a warning
+// alongside the untransformed list.
+func loadNotifications(client GitHubClient, postFetchCmd string, query notificationsQuery, etag string, progress func(fetched int)) (notificationsPage, string, error) {
+	inbox, err := fetchNotifications(client, query, etag, progress)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to fetch details: %v", err)
+		return notificationsPage{}, "", err
 	}
+	var warning string
+	inbox.notifications, warning = applyPostFetchHook(postFetchCmd, inbox.notifications)
+	return inbox, warning, nil
+}
 
-	var data map[string]interface{}
-	if err := json.Unmarshal(output, &data); err != nil {
-		return "", "", fmt.Errorf("failed to parse details: %v", err)
+// loadNotificationsPage is loadNotifications for a single page; the hook
+// sees one page at a time
+func loadNotificationsPage(client GitHubClient, postFetchCmd string, query notificationsQuery, etag string, page int) (notificationsPage, string, error) {
+	batch, err := client.NotificationsPage(page, query, etag)
+	if err != nil {
+		return notificationsPage{}, "", err
+	}
+	var warning string
+	batch.notifications, warning = applyPostFetchHook(postFetchCmd, batch.notifications)
+	return batch, warning, nil
+}
+
+// applyPostFetchHook runs notifications through the hook, if there is one,
+// returning a warning and the untransformed list if it fails
+func applyPostFetchHook(postFetchCmd string, notifications []Notification) ([]Notification, string) {
+	if postFetchCmd == "" {
+		return notifications, ""
 	}
 
-	body, ok := data["body"].(string)
-	if !ok {
-		return "", "", fmt.Errorf("could not find body in response")
+	// Fail closed: a broken hook shouldn't hide the inbox
+	transformed, err := runPostFetchHook(postFetchCmd, notifications)
+	if err != nil {
+		return notifications, fmt.Sprintf("post_fetch_cmd failed, showing unfiltered list: %v", err)
 	}
+	return transformed, ""
+}
 
-	user, ok := data["user"].(map[string]interface{})
-	if !ok {
-		return body, "", nil // Not all items have a user, so don't error
+// runPostFetchHook pipes the notifications as JSON through a user command and
+// parses its stdout as the replacement list
+func runPostFetchHook(command string, notifications []Notification) ([]Notification, error) {
+	input, err := json.Marshal(notifications)
+	if err != nil {
+		return nil, err
 	}
 
-	author, ok := user["login"].(string)
-	if !ok {
-		return body, "", nil // Not all users have a login, so don't error
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(input)
+	output, err := cmd.Output()
+	logCommand(cmd, err)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
 	}
 
-	return body, author, nil
+	var transformed []Notification
+	if err := json.Unmarshal(output, &transformed); err != nil {
+		log.Printf("failed to parse post_fetch_cmd output: %v", err)
+		return nil, fmt.Errorf("failed to parse output: %v", err)
+	}
+	return transformed, nil
 }
 
-func fetchDetailsCmd(url string, notificationType string) tea.Cmd {
+func markAsReadCmd(client GitHubClient, id string, keep bool, dryRun bool) tea.Cmd {
 	return func() tea.Msg {
-		body, author, err := fetchDetails(url, notificationType)
+		if dryRun {
+			log.Printf("dry run: mark %s as read", id)
+			return notificationMarkedMsg{id: id, keep: keep}
+		}
+		err := client.MarkRead(id)
+		if isAuthError(err) {
+			return authErrorMsg{err: err, pending: true}
+		}
 		if err != nil {
-			return errorMsg(err)
+			return actionFailedMsg{err: fmt.Errorf("failed to mark as read: %v", err)}
 		}
-		return detailsLoadedMsg{body: body, author: author}
+		return notificationMarkedMsg{id: id, keep: keep}
 	}
 }
 
-func renderMarkdown(content string, width int) (string, error) {
-	r, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(width),
-	)
-	if err != nil {
-		return "", err
+func markAsDoneCmd(client GitHubClient, id string, dryRun bool) tea.Cmd {
+	return func() tea.Msg {
+		if dryRun {
+			log.Printf("dry run: mark %s as done", id)
+			return notificationDoneMsg(id)
+		}
+		err := client.MarkDone(id)
+		if isAuthError(err) {
+			return authErrorMsg{err: err, pending: true}
+		}
+		if err != nil {
+			return actionFailedMsg{err: fmt.Errorf("failed to mark as done: %v", err)}
+		}
+		return notificationDoneMsg(id)
 	}
-	return r.Render(content)
 }
 
-// Bubble Tea Model Implementation
-func initialModel() Model {
-	return Model{
-		notifications:  []Notification{},
-		selectedIndex:  0,
-		loading:        true,
-		statusMessage:  "Loading notifications...",
-		summaryCache:   make(map[string]detailsLoadedMsg),
-		summaryScroll:  0,
-		terminalWidth:  80,
-		terminalHeight: 24,
+func unsubscribeCmd(client GitHubClient, id string, dryRun bool) tea.Cmd {
+	return func() tea.Msg {
+		if dryRun {
+			log.Printf("dry run: unsubscribe from %s", id)
+			return unsubscribedMsg(id)
+		}
+		err := client.Unsubscribe(id)
+		if isAuthError(err) {
+			return authErrorMsg{err: err, pending: true}
+		}
+		if err != nil {
+			return actionFailedMsg{err: fmt.Errorf("failed to unsubscribe: %v", err)}
+		}
+		return unsubscribedMsg(id)
 	}
 }
 
-func (m Model) Init() tea.Cmd {
-	return fetchNotificationsCmd()
+func subscribeCmd(client GitHubClient, id string, dryRun bool) tea.Cmd {
+	return func() tea.Msg {
+		if dryRun {
+			log.Printf("dry run: subscribe to %s", id)
+			return subscribedMsg(id)
+		}
+		err := client.Subscribe(id)
+		if isAuthError(err) {
+			return authErrorMsg{err: err, pending: true}
+		}
+		if err != nil {
+			return actionFailedMsg{err: fmt.Errorf("failed to subscribe: %v", err)}
+		}
+		return subscribedMsg(id)
+	}
 }
 
-func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-
-	case tea.WindowSizeMsg:
-		m.terminalWidth = msg.Width
-		m.terminalHeight = msg.Height
-		return m, nil
-
-	case tea.KeyMsg:
-		return m.handleKeyPress(msg)
-
-	case notificationsLoadedMsg:
-		m.notifications = []Notification(msg)
-		m.loading = false
-		m.err = nil
-		m.statusMessage = fmt.Sprintf("Loaded %d notifications", len(m.notifications))
-		if len(m.notifications) == 0 {
-			m.statusMessage = "No notifications found"
+// fetchViewerCmd looks up who is logged in, to tell their own activity
+// apart. Without it nothing is dimmed, so failures are ignored.
+func fetchViewerCmd(client GitHubClient) tea.Cmd {
+	return func() tea.Msg {
+		login, err := client.Viewer()
+		if err != nil {
+			return nil
 		}
-		return m, nil
+		return viewerMsg(login)
+	}
+}
 
-	case notificationMarkedMsg:
-		// Remove the marked notification from the list
-		id := string(msg)
-		for i, notification := range m.notifications {
-			if notification.ID == id {
-				m.notifications = append(m.notifications[:i], m.notifications[i+1:]...)
-				// Adjust selected index if necessary
-				if m.selectedIndex >= len(m.notifications) && len(m.notifications) > 0 {
-					m.selectedIndex = len(m.notifications) - 1
-				}
-				if len(m.notifications) == 0 {
-					m.selectedIndex = 0
-				}
-				break
-			}
+// fetchRateLimitCmd reads the remaining API quota for the status bar. It's
+// informational, so failures are ignored.
+func fetchRateLimitCmd(client GitHubClient) tea.Cmd {
+	return func() tea.Msg {
+		info, err := client.RateLimit()
+		if err != nil || info.Limit == 0 {
+			return nil
 		}
-		m.statusMessage = "Notification marked as read"
-		return m, nil
+		return rateLimitInfoMsg(info)
+	}
+}
 
-	case detailsLoadedMsg:
-		m.summaryLoading = false
-		notification := m.notifications[m.selectedIndex]
-		m.summaryCache[notification.ID] = msg
-		author := msg.author
-		if author != "" {
-			author = fmt.Sprintf("by @%s", author)
-		}
-		m.summaryHeader = fmt.Sprintf("Repository: %s\nReason: %s\nType: %s %s\n\n%s",
-			notification.RepoName(),
-			notification.Reason,
-			notification.TypeDisplay(),
-			author,
-			notification.Subject.Title)
-		m.summaryBody = msg.body
-		m.statusMessage = "Summary loaded"
+// refreshEvery is how often auto-refresh polls: the configured interval, or
+// longer if GitHub asks for it
+func (m Model) refreshEvery() time.Duration {
+	return max(m.refreshInterval, m.pollInterval)
+}
 
-		// Render markdown and set lines
-		renderedBody, err := renderMarkdown(m.summaryBody, m.terminalWidth-8)
+func autoRefreshTickCmd(interval time.Duration, gen int) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return autoRefreshTickMsg(gen)
+	})
+}
+
+// bulkMarkBatchSize is how many threads are marked concurrently during a
+// bulk mark, and so how often progress is reported
+const bulkMarkBatchSize = 10
+
+// saveSnoozesCmd persists the snoozes. The map is copied, since the model
+// may change it before the command runs.
+func saveSnoozesCmd(snoozes map[string]time.Time) tea.Cmd {
+	saved := make(map[string]time.Time, len(snoozes))
+	for id, wake := range snoozes {
+		saved[id] = wake
+	}
+	return func() tea.Msg {
+		if err := saveSnoozes(saved); err != nil {
+			return statusMsg(fmt.Sprintf("Failed to save snoozes: %v", err))
+		}
+		return nil
+	}
+}
+
+// maxDesktopNotifications is how many arrivals get a desktop notification of
+// their own; beyond that they're summarised in one
+const maxDesktopNotifications = 3
+
+// desktopNotifyCmd raises a desktop notification for newly arrived
+// notifications. Failures, e.g. no notification daemon, are ignored.
+func desktopNotifyCmd(arrivals []Notification) tea.Cmd {
+	return func() tea.Msg {
+		if len(arrivals) > maxDesktopNotifications {
+			_ = beeep.Notify("GitHub Notifications",
+				fmt.Sprintf("%d new notifications", len(arrivals)), "")
+			return nil
+		}
+		for _, notification := range arrivals {
+			_ = beeep.Notify(notification.RepoName(), notification.Subject.Title, "")
+		}
+		return nil
+	}
+}
+
+// markBatchCmd marks a batch of threads as read, or as done, concurrently
+func markBatchCmd(client GitHubClient, ids []string, done bool, dryRun bool) tea.Cmd {
+	mark := client.MarkRead
+	if done {
+		mark = client.MarkDone
+	}
+	if dryRun {
+		outcome := "read"
+		if done {
+			outcome = "done"
+		}
+		mark = func(id string) error {
+			log.Printf("dry run: mark %s as %s", id, outcome)
+			return nil
+		}
+	}
+	return func() tea.Msg {
+		errs := make([]error, len(ids))
+		var wg sync.WaitGroup
+		for i, id := range ids {
+			wg.Add(1)
+			go func(i int, id string) {
+				defer wg.Done()
+				errs[i] = mark(id)
+			}(i, id)
+		}
+		wg.Wait()
+
+		var result bulkMarkedMsg
+		for i, id := range ids {
+			if errs[i] != nil {
+				result.failed++
+				result.authFailed = result.authFailed || isAuthError(errs[i])
+			} else {
+				result.marked = append(result.marked, id)
+			}
+		}
+		return result
+	}
+}
+
+func openInBrowserCmd(client GitHubClient, notification Notification) tea.Cmd {
+	return func() tea.Msg {
+		err := client.OpenInBrowser(notification)
+		if err != nil {
+			return errorMsg(fmt.Errorf("failed to open in browser: %v", err))
+		}
+		return notificationOpenedMsg(notification.ID)
+	}
+}
+
+// openSelectedDelay spaces out browser launches when opening several
+// notifications, since some browsers drop tabs opened in quick succession
+const openSelectedDelay = 300 * time.Millisecond
+
+// openSelectedConfirmThreshold is how many tabs can be opened at once
+// without asking first
+const openSelectedConfirmThreshold = 5
+
+// openSelectedCmd opens each notification in the browser, one at a time
+func openSelectedCmd(client GitHubClient, notifications []Notification) tea.Cmd {
+	return func() tea.Msg {
+		var result selectedOpenedMsg
+		for i, notification := range notifications {
+			if i > 0 {
+				time.Sleep(openSelectedDelay)
+			}
+			if err := client.OpenInBrowser(notification); err != nil {
+				result.failed++
+			} else {
+				result.opened++
+			}
+		}
+		return result
+	}
+}
+
+// openReviewInBrowserCmd opens the "Files changed" tab of a pull request we
+// were asked to review, falling back to the normal destination for anything
+// else
+func openReviewInBrowserCmd(client GitHubClient, notification Notification) tea.Cmd {
+	return func() tea.Msg {
+		open := client.OpenInBrowser
+		if isReviewRequest(notification) {
+			open = client.OpenReview
+		}
+		if err := open(notification); err != nil {
+			return errorMsg(fmt.Errorf("failed to open in browser: %v", err))
+		}
+		return notificationOpenedMsg(notification.ID)
+	}
+}
+
+func openRepoListCmd(client GitHubClient, notification Notification) tea.Cmd {
+	return func() tea.Msg {
+		if err := client.OpenRepoList(notification); err != nil {
+			return errorMsg(fmt.Errorf("failed to open in browser: %v", err))
+		}
+		return statusMsg("Opened " + notification.RepoName() + " in browser")
+	}
+}
+
+// issueReference returns how to refer to an issue, pull request or discussion
+// in GitHub markdown, e.g. "#123". Other subjects aren't numbered.
+func issueReference(notification Notification) (string, bool) {
+	switch notification.Subject.Type {
+	case "Issue", "PullRequest", "Discussion":
+	default:
+		return "", false
+	}
+	number := extractIssueNumber(notification.Subject.URL)
+	if _, err := strconv.Atoi(number); err != nil {
+		return "", false
+	}
+	return "#" + number, true
+}
+
+func copyReferenceCmd(notification Notification) tea.Cmd {
+	return func() tea.Msg {
+		reference, ok := issueReference(notification)
+		if !ok {
+			return errorMsg(fmt.Errorf("%s notifications have no number to copy", notification.TypeDisplay()))
+		}
+		if err := clipboard.WriteAll(reference); err != nil {
+			return errorMsg(fmt.Errorf("failed to copy number: %v", err))
+		}
+		return statusMsg("Copied " + reference)
+	}
+}
+
+// copyURLsCmd copies the web URLs of notifications, one per line
+func copyURLsCmd(client GitHubClient, notifications []Notification) tea.Cmd {
+	return func() tea.Msg {
+		urls := make([]string, len(notifications))
+		for i, notification := range notifications {
+			urls[i] = notificationWebURL(client, notification)
+		}
+		if err := clipboard.WriteAll(strings.Join(urls, "\n")); err != nil {
+			return errorMsg(fmt.Errorf("failed to copy URLs: %v", err))
+		}
+		if len(urls) == 1 {
+			return statusMsg("Copied 1 URL")
+		}
+		return statusMsg(fmt.Sprintf("Copied %d URLs", len(urls)))
+	}
+}
+
+func copyURLCmd(client GitHubClient, notification Notification) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(notificationWebURL(client, notification)); err != nil {
+			return errorMsg(fmt.Errorf("failed to copy URL: %v", err))
+		}
+		return statusMsg("Copied URL")
+	}
+}
+
+func fetchDetails(url string, notificationType string) (detailsLoadedMsg, error) {
+	var details detailsLoadedMsg
+
+	output, err := ghOutput("api", url)
+	if err != nil {
+		if isAuthError(err) {
+			return details, err
+		}
+		return details, fmt.Errorf("failed to fetch details: %v", err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(output, &data); err != nil {
+		log.Printf("failed to parse details of %s: %v", url, err)
+		return details, fmt.Errorf("failed to parse details: %v", err)
+	}
+
+	// Not every subject has a body, state, labels or user, so none of
+	// these are errors when missing
+	details.body, _ = data["body"].(string)
+
+	details.state, _ = data["state"].(string)
+	if merged, _ := data["merged"].(bool); merged {
+		details.state = "merged"
+	}
+
+	if labels, ok := data["labels"].([]interface{}); ok {
+		for _, label := range labels {
+			if label, ok := label.(map[string]interface{}); ok {
+				if name, ok := label["name"].(string); ok {
+					details.labels = append(details.labels, name)
+				}
+			}
+		}
+	}
+
+	if user, ok := data["user"].(map[string]interface{}); ok {
+		details.author, _ = user["login"].(string)
+		details.authorType, _ = user["type"].(string)
+	}
+
+	return details, nil
+}
+
+// detailFetchWorkers bounds the parallel requests made by fetchSubjectDetailsCmd
+const detailFetchWorkers = 8
+
+// fetchSubjectDetailsCmd fetches the details of each notification's subject,
+// either in batched GraphQL queries or on a pool of REST workers. Results are
+// streamed back one subjectDetailMsg at a time, as they arrive, each carrying
+// the channel to read the next from.
+func fetchSubjectDetailsCmd(client GitHubClient, notifications []Notification, useGraphQL bool) tea.Cmd {
+	results := make(chan subjectDetailMsg, len(notifications))
+	jobs := make(chan Notification, len(notifications))
+
+	var wg sync.WaitGroup
+	for i := 0; i < detailFetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for notification := range jobs {
+				details, err := client.SubjectDetails(notification.Subject.URL, notification.Subject.Type)
+				results <- subjectDetailMsg{notification: notification, details: details, ok: err == nil}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		if !useGraphQL {
+			for _, notification := range notifications {
+				jobs <- notification
+			}
+			return
+		}
+		// A failed batch falls back to REST for its subjects
+		for start := 0; start < len(notifications); start += graphQLBatchSize {
+			batch := notifications[start:min(start+graphQLBatchSize, len(notifications))]
+			details, err := client.SubjectsGraphQL(batch)
+			for _, notification := range batch {
+				if err != nil {
+					jobs <- notification
+					continue
+				}
+				subject, ok := details[notification.Subject.URL]
+				results <- subjectDetailMsg{notification: notification, details: subject, ok: ok}
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return waitForSubjectDetailCmd(results)
+}
+
+// waitForSubjectDetailCmd delivers the next streamed result, if any remain
+func waitForSubjectDetailCmd(results <-chan subjectDetailMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-results
+		if !ok {
+			return nil
+		}
+		msg.next = results
+		return msg
+	}
+}
+
+func fetchDetailsCmd(client GitHubClient, url string, notificationType string) tea.Cmd {
+	return func() tea.Msg {
+		details, err := client.SubjectDetails(url, notificationType)
+		if isAuthError(err) {
+			return authErrorMsg{err: err}
+		}
+		if err != nil {
+			return errorMsg(err)
+		}
+		return details
+	}
+}
+
+// minMarkdownWidth keeps glamour's word wrap sane on very narrow terminals
+const minMarkdownWidth = 20
+
+// renderMarkdown renders a subject body for the terminal, wrapped to width
+func renderMarkdown(content string, width int) (string, error) {
+	if width < minMarkdownWidth {
+		width = minMarkdownWidth
+	}
+	r, err := glamour.NewTermRenderer(
+		glamour.WithAutoStyle(),
+		glamour.WithWordWrap(width),
+		// glamour assumes true color otherwise, ignoring NO_COLOR
+		glamour.WithColorProfile(lipgloss.ColorProfile()),
+	)
+	if err != nil {
+		return "", err
+	}
+	return r.Render(content)
+}
+
+// Bubble Tea Model Implementation
+func initialModel(config Config, client GitHubClient, query notificationsQuery) Model {
+	m := Model{
+		config:          config,
+		client:          client,
+		sortMode:        sortNewest,
+		density:         densityCompact,
+		notifications:   []Notification{},
+		selectedIndex:   0,
+		loading:         true,
+		statusMessage:   "Loading notifications from " + ghHost + "...",
+		detailCache:     make(map[string]detailsLoadedMsg),
+		subjectStates:   make(map[string]subjectState),
+		snoozed:         loadSnoozes(time.Now()),
+		selected:        make(map[string]bool),
+		terminalWidth:   80,
+		terminalHeight:  24,
+		fetching:        true,
+		spinner:         spinner.New(spinner.WithSpinner(glyphs.spinner), spinner.WithStyle(statusStyle)),
+		autoRefresh:     config.AutoRefresh,
+		refreshInterval: config.RefreshInterval,
+		sessionStart:    time.Now(),
+		typeFilter:      config.DefaultFilter.Type,
+		reasonFilter:    config.DefaultFilter.Reason,
+		unreadOnly:      config.DefaultFilter.UnreadOnly,
+		showAll:         query.all,
+		repo:            query.repo,
+	}
+
+	// Show the last fetched list straight away while Init fetches a fresh one.
+	// It's the whole inbox, so it would misrepresent all threads or one
+	// repository.
+	if query.all || query.repo != "" {
+		return m
+	}
+	if cached, ok := loadCache(); ok {
+		m.notifications = cached
+		m.cached = true
+		m.loading = false
+		m.statusMessage = "Showing cached notifications, refreshing from " + ghHost + "..."
+	}
+	return m
+}
+
+func (m Model) Init() tea.Cmd {
+	if m.autoRefresh {
+		return tea.Batch(
+			fetchNotificationsCmd(m.client, m.config, m.query()),
+			fetchRateLimitCmd(m.client),
+			fetchViewerCmd(m.client),
+			autoRefreshTickCmd(m.refreshEvery(), m.refreshGen),
+			m.spinner.Tick,
+		)
+	}
+	return tea.Batch(fetchNotificationsCmd(m.client, m.config, m.query()), fetchRateLimitCmd(m.client), fetchViewerCmd(m.client), m.spinner.Tick)
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.terminalWidth = msg.Width
+		m.terminalHeight = msg.Height
+		// Views laid out for the old width are laid out again
+		if m.showingSummary && !m.summaryLoading {
+			m.renderSummary()
+		}
+		if m.commenting {
+			m.commentInput.SetWidth(m.commentWidth())
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKeyPress(msg)
+
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+
+	case notificationsLoadedMsg:
+		m.fetching = false
+		// Remember where we were so the cursor stays on the same item, or
+		// its nearest surviving neighbour, however the list changes
+		nearby := m.selectionNeighbours()
+
+		// Switching between the inbox and all threads changes what's
+		// returned, so neither of the comparisons below hold
+		sameMode := msg.query == m.listedQuery
+		m.listedQuery = msg.query
+		m.etag = msg.etag
+		if msg.pollInterval > m.refreshInterval && msg.pollInterval != m.pollInterval {
+			log.Printf("GitHub asks for %s between polls, overriding refresh_interval %s",
+				msg.pollInterval, m.refreshInterval)
+		}
+		if msg.pollInterval > 0 {
+			m.pollInterval = msg.pollInterval
+		}
+
+		// Reconcile against the server: anything we had that is no longer
+		// returned was read or marked done elsewhere
+		fresh := make(map[string]bool, len(msg.notifications))
+		for _, notification := range msg.notifications {
+			fresh[notification.ID] = true
+		}
+		cleared := 0
+		for _, notification := range m.notifications {
+			// The cache may be days old, so don't count what changed since.
+			// With only the first page there's nothing to compare the rest
+			// against either.
+			if !fresh[notification.ID] && !m.cached && !msg.hasMore && sameMode {
+				cleared++
+				delete(m.detailCache, notification.Subject.URL)
+			}
+		}
+
+		// Anything not seen on the previous fetch is new; the first fetch,
+		// cached or not, has nothing to compare against
+		var arrivals []Notification
+		if m.autoRefresh && m.config.DesktopNotifications && !m.lastRefresh.IsZero() && sameMode {
+			previous := make(map[string]bool, len(m.notifications))
+			for _, notification := range m.notifications {
+				previous[notification.ID] = true
+			}
+			for _, notification := range msg.notifications {
+				if !previous[notification.ID] {
+					arrivals = append(arrivals, notification)
+				}
+			}
+		}
+
+		m.notifications = msg.notifications
+		m.page = 1
+		m.hasMore = msg.hasMore
+		// Any later page in flight was cut from the old list
+		m.pageGen++
+		m.pageLoading = false
+		m.loading = false
+		m.cached = false
+		m.err = nil
+		m.authExpired = false
+		m.lastRefresh = time.Now()
+		m.selectByID(nearby...)
+		m.pruneSelected()
+		m.clampRepoPicker(len(m.repoPickerEntries()))
+		m.statusMessage = fmt.Sprintf("Loaded %d notifications", len(m.notifications))
+		if len(m.notifications) == 0 {
+			m.statusMessage = "No notifications found"
+		} else if m.filtered() {
+			m.statusMessage = m.filterStatus()
+		}
+		if cleared > 0 {
+			m.statusMessage += fmt.Sprintf(" (%d cleared elsewhere)", cleared)
+		}
+		if m.hasMore {
+			m.statusMessage += " (more below)"
+		}
+		if msg.warning != "" {
+			m.statusMessage = "Warning: " + msg.warning
+		}
+		// Check what the fetch cost
+		cmds := []tea.Cmd{fetchRateLimitCmd(m.client)}
+		if expireSnoozes(m.snoozed, time.Now()) {
+			cmds = append(cmds, saveSnoozesCmd(m.snoozed))
+		}
+		if len(arrivals) > 0 {
+			cmds = append(cmds, desktopNotifyCmd(arrivals))
+		}
+		cmds = append(cmds, m.fetchStaleSubjectsCmd())
+		return m, tea.Batch(cmds...)
+
+	case notificationsUnchangedMsg:
+		// The list shown is still current
+		m.fetching = false
+		m.err = nil
+		m.authExpired = false
+		m.lastRefresh = time.Now()
+		m.statusMessage = "No changes since the last refresh"
+		if expireSnoozes(m.snoozed, time.Now()) {
+			return m, saveSnoozesCmd(m.snoozed)
+		}
+		return m, nil
+
+	case pageLoadedMsg:
+		// A refresh since the request started back to page 1; drop it
+		if msg.gen != m.pageGen {
+			return m, nil
+		}
+		m.pageLoading = false
+		if msg.err != nil {
+			m.authExpired = m.authExpired || isAuthError(msg.err)
+			m.statusMessage = fmt.Sprintf("Failed to load more: %v", msg.err)
+			return m, nil
+		}
+		present := make(map[string]bool, len(m.notifications))
+		for _, notification := range m.notifications {
+			present[notification.ID] = true
+		}
+		// Pages shift as notifications are read, so skip any repeats
+		added := 0
+		for _, notification := range msg.notifications {
+			if !present[notification.ID] {
+				m.notifications = append(m.notifications, notification)
+				added++
+			}
+		}
+		m.page = msg.page
+		m.hasMore = msg.hasMore
+		m.statusMessage = fmt.Sprintf("Loaded %d more", added)
+		if m.hasMore {
+			m.statusMessage += " (more below)"
+		}
+		if msg.warning != "" {
+			m.statusMessage = "Warning: " + msg.warning
+		}
+		return m, m.fetchStaleSubjectsCmd()
+
+	case subjectDetailMsg:
+		// Failures leave the state unknown rather than being reported, and
+		// aren't retried until the notification is next updated
+		notification := msg.notification
+		// Learning the author can hide a bot's notification, possibly the
+		// selected one
+		var nearby []string
+		if m.hideBots {
+			nearby = m.selectionNeighbours()
+		}
+		m.subjectStates[notification.Subject.URL] = subjectState{
+			state:      msg.details.state,
+			author:     msg.details.author,
+			authorType: msg.details.authorType,
+			asOf:       notification.UpdatedAt,
+		}
+		if m.hideBots {
+			m.selectByID(nearby...)
+		}
+		if msg.ok {
+			// Opening the summary later needn't fetch again
+			m.detailCache[notification.Subject.URL] = msg.details
+		}
+		return m, waitForSubjectDetailCmd(msg.next)
+
+	case notificationMarkedMsg:
+		m.inFlight--
+		m.readCount++
+		m.statusMessage = "Notification marked as read" + m.dryRunNote()
+		if msg.keep {
+			current, _ := m.selectedNotification()
+			for i := range m.notifications {
+				if m.notifications[i].ID == msg.id {
+					m.notifications[i].Unread = false
+				}
+			}
+			// It may have moved, sorting unread first, or dropped out of
+			// the unread-only filter. Stay with the cursor if it moved on;
+			// otherwise the next row takes its place, as when removing.
+			if current.ID != msg.id {
+				m.selectByID(current.ID)
+			} else {
+				m.clampSelection()
+			}
+			return m, nil
+		}
+		// Remove the marked notification from the list
+		m.pushUndo(msg.id)
+		m.removeNotification(msg.id)
+		return m, nil
+
+	case notificationDoneMsg:
+		m.inFlight--
+		m.removeNotification(string(msg))
+		m.doneCount++
+		m.statusMessage = "Marked as done" + m.dryRunNote()
+		return m, nil
+
+	case unsubscribedMsg:
+		m.inFlight--
+		m.removeNotification(string(msg))
+		m.readCount++
+		m.statusMessage = "Unsubscribed" + m.dryRunNote()
+		return m, nil
+
+	case subscribedMsg:
+		m.inFlight--
+		m.statusMessage = "Subscribed" + m.dryRunNote()
+		return m, nil
+
+	case commentPostedMsg:
+		m.inFlight--
+		if msg.err != nil {
+			m.statusMessage = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+		m.statusMessage = "Comment posted" + m.dryRunNote()
+		return m, nil
+
+	case bulkMarkStartMsg:
+		m.bulkMarkDone = msg.done
+		m.bulkPending = msg.ids
+		m.bulkTotal = len(msg.ids)
+		m.bulkDone = 0
+		m.bulkFailed = 0
+		m.statusMessage = fmt.Sprintf("Marked 0/%d", m.bulkTotal)
+		return m, tea.Batch(m.nextBulkMarkCmd(), m.spinner.Tick)
+
+	case bulkMarkedMsg:
+		// Stay on the same notification, or its nearest unmarked neighbour
+		nearby := m.selectionNeighbours()
+		for _, id := range msg.marked {
+			if !m.bulkMarkDone {
+				m.pushUndo(id)
+			}
+			m.removeNotification(id)
+		}
+		m.selectByID(nearby...)
+		if m.bulkMarkDone {
+			m.doneCount += len(msg.marked)
+		} else {
+			m.readCount += len(msg.marked)
+		}
+		m.bulkDone += len(msg.marked) + msg.failed
+		m.bulkFailed += msg.failed
+		m.authExpired = m.authExpired || msg.authFailed
+		if len(m.bulkPending) > 0 {
+			m.statusMessage = fmt.Sprintf("Marked %d/%d", m.bulkDone, m.bulkTotal)
+			return m, m.nextBulkMarkCmd()
+		}
+
+		m.selected = make(map[string]bool)
+		outcome := "read"
+		if m.bulkMarkDone {
+			outcome = "done"
+		}
+		m.statusMessage = fmt.Sprintf("Marked %d/%d as %s", m.bulkDone-m.bulkFailed, m.bulkTotal, outcome) + m.dryRunNote()
+		if m.bulkFailed > 0 {
+			m.statusMessage += fmt.Sprintf(" (%d failed)", m.bulkFailed)
+		}
+		return m, nil
+
+	case notificationOpenedMsg:
+		m.openedCount++
+		m.statusMessage = "Opened in browser"
+		return m, nil
+
+	case selectedOpenedMsg:
+		m.openedCount += msg.opened
+		m.statusMessage = fmt.Sprintf("Opened %d in browser", msg.opened)
+		if msg.failed > 0 {
+			m.statusMessage += fmt.Sprintf(" (%d failed)", msg.failed)
+		}
+		return m, nil
+
+	case detailsLoadedMsg:
+		m.summaryLoading = false
+		notification, ok := m.selectedNotification()
+		if !ok {
+			return m, nil
+		}
+		m.detailCache[notification.Subject.URL] = msg
+		if msg.state != "" {
+			m.subjectStates[notification.Subject.URL] = subjectState{
+				state:      msg.state,
+				author:     msg.author,
+				authorType: msg.authorType,
+				asOf:       notification.UpdatedAt,
+			}
+		}
+		m.setSummary(notification, msg)
+		m.statusMessage = "Summary loaded"
+		return m, nil
+
+	case rateLimitInfoMsg:
+		m.rateLimit = rateLimitInfo(msg)
+		return m, nil
+
+	case viewerMsg:
+		m.viewer = string(msg)
+		return m, nil
+
+	case rateLimitedMsg:
+		m.loading = false
+		m.fetching = false
+		m.rateLimitReset = time.Time(msg)
+		m.statusMessage = m.rateLimitStatus()
+		return m, nil
+
+	case fetchProgressMsg:
+		m.statusMessage = fmt.Sprintf("Fetched %d so far...", msg.fetched)
+		return m, waitForFetchCmd(msg.next)
+
+	case fetchRetryMsg:
+		retry := int(msg)
+		m.statusMessage = fmt.Sprintf("Retrying (%d/%d)...", retry, maxFetchRetries)
+		client, config, query := m.client, m.config, m.query()
+		return m, tea.Tick(fetchRetryDelay(retry), func(time.Time) tea.Msg {
+			return fetchAttemptCmd(client, config, query, "", retry)()
+		})
+
+	case autoRefreshTickMsg:
+		if !m.autoRefresh || int(msg) != m.refreshGen {
+			return m, nil // stale tick from before a toggle
+		}
+		// Back off until a rate limit resets
+		if m.fetching || m.rateLimited() {
+			return m, autoRefreshTickCmd(m.refreshEvery(), m.refreshGen)
+		}
+		m.fetching = true
+		return m, tea.Batch(
+			fetchAttemptCmd(m.client, m.config, m.query(), m.refreshETag(), 0),
+			autoRefreshTickCmd(m.refreshEvery(), m.refreshGen),
+			m.spinner.Tick,
+		)
+
+	case spinner.TickMsg:
+		// Let the animation stop once nothing is loading or pending;
+		// fetches and actions restart it
+		if !m.fetching && m.pendingActions() == 0 {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case actionFailedMsg:
+		m.inFlight--
+		return m.Update(errorMsg(msg.err))
+
+	case authErrorMsg:
+		if msg.pending {
+			m.inFlight--
+		}
+		if m.summaryLoading {
+			m.summaryLoading = false
+			m.showingSummary = false
+		}
+		m.fetching = false
+		if m.loading {
+			// Nothing to show yet, so the error screen explains it
+			m.loading = false
+			m.err = msg.err
+			return m, nil
+		}
+		m.authExpired = true
+		m.statusMessage = fmt.Sprintf("Error: %v", msg.err)
+		return m, nil
+
+	case errorMsg:
+		m.loading = false
+		m.fetching = false
+		if m.cached {
+			// Keep the cached list usable, e.g. when offline
+			m.statusMessage = fmt.Sprintf("Error: %v (showing cached notifications)", error(msg))
+			return m, nil
+		}
+		m.err = error(msg)
+		m.statusMessage = fmt.Sprintf("Error: %v", m.err)
+		return m, nil
+
+	case statusMsg:
+		m.statusMessage = string(msg)
+		return m, nil
+	}
+
+	return m, nil
+}
+
+// setSummary fills the summary pane for a notification from its details
+func (m *Model) setSummary(notification Notification, details detailsLoadedMsg) {
+	author := details.author
+	if author != "" {
+		author = fmt.Sprintf("by @%s", author)
+	}
+	m.summaryHeader = fmt.Sprintf("Repository: %s\nReason: %s\nType: %s %s",
+		notification.RepoName(),
+		renderReason(notification.Reason),
+		notification.TypeDisplay(),
+		author)
+	if details.state != "" {
+		m.summaryHeader += "\nState: " + details.state
+	}
+	if len(details.labels) > 0 {
+		m.summaryHeader += "\nLabels: " + strings.Join(details.labels, ", ")
+	}
+	if notification.UpdatedSinceRead() {
+		m.summaryHeader += fmt.Sprintf("\nNew activity since you read it on %s",
+			notification.LastReadAt.Format("01-02 15:04"))
+	}
+	m.summaryHeader += "\n\n" + notification.Subject.Title
+
+	body := details.body
+	if body == "" {
+		body = "_No description provided._"
+	}
+	m.summaryBody = body
+	m.summaryPlain = false
+	m.renderSummary()
+}
+
+// showRawJSON opens the summary pane on the notification as GitHub returned
+// it, for checking which fields are populated
+func (m *Model) showRawJSON(notification Notification) {
+	raw, err := json.MarshalIndent(notification, "", "  ")
+	if err != nil {
+		m.statusMessage = fmt.Sprintf("Error: %v", err)
+		return
+	}
+	m.showingSummary = true
+	m.summaryLoading = false
+	m.summaryViewport.GotoTop()
+	m.summaryHeader = "Raw JSON\n\n" + notification.Subject.Title
+	m.summaryBody = string(raw)
+	m.summaryPlain = true
+	m.renderSummary()
+	m.statusMessage = ""
+}
+
+// renderSummary lays out the summary pane's lines for the current terminal
+// width, rendering the body's markdown
+func (m *Model) renderSummary() {
+	width := m.terminalWidth - 8 // border and padding
+	var renderedBody string
+	if m.summaryPlain {
+		renderedBody = ansi.Wrap(m.summaryBody, max(width, minMarkdownWidth), "")
+	} else {
+		var err error
+		renderedBody, err = renderMarkdown(m.summaryBody, width)
 		if err != nil {
 			renderedBody = m.summaryBody // fallback
 		}
-		fullContent := m.summaryHeader + "\n\n---\n\n" + renderedBody
-		m.summaryLines = strings.Split(fullContent, "\n")
+	}
+	header := ansi.Wrap(m.summaryHeader, max(width, minMarkdownWidth), "")
+	fullContent := header + "\n\n---\n\n" + renderedBody
+
+	// Sized to the content, so short summaries get a small box
+	m.summaryViewport.Width = lipgloss.Width(fullContent)
+	m.summaryViewport.Height = min(strings.Count(fullContent, "\n")+1, m.summaryHeight())
+	m.summaryViewport.SetContent(fullContent)
+}
+
+// summaryHeight is how many lines of the summary pane fit on screen, inside
+// its border, padding and scroll indicators, above the help line
+func (m Model) summaryHeight() int {
+	return max(m.terminalHeight-7, 1)
+}
+
+// dryRunNote marks status messages for actions that were only pretended
+func (m Model) dryRunNote() string {
+	if m.dryRun {
+		return " (dry run)"
+	}
+	return ""
+}
+
+// jumpTo moves the selection to the 1-based row number typed as buffer
+func (m Model) jumpTo(buffer string) (tea.Model, tea.Cmd) {
+	row, err := strconv.Atoi(buffer)
+	visible := m.visibleNotifications()
+	if err != nil || row < 1 || row > len(visible) {
+		m.statusMessage = fmt.Sprintf("No row %s (1-%d)", buffer, len(visible))
+		return m, nil
+	}
+	m.selectedIndex = row - 1
+	m.statusMessage = ""
+	return m, m.loadMoreIfNearEnd()
+}
+
+func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.confirming {
+		key := msg.String()
+		if m.confirmingQuit && m.config.keyMap.Action(key) == actionQuit {
+			key = "y"
+		}
+		switch key {
+		case "y", "Y":
+			cmd := m.onConfirm
+			m.confirming = false
+			m.confirmingQuit = false
+			m.confirmPrompt = ""
+			m.onConfirm = nil
+			return m, cmd
+		case "n", "N", "esc":
+			m.confirming = false
+			m.confirmingQuit = false
+			m.confirmPrompt = ""
+			m.onConfirm = nil
+			m.statusMessage = "Cancelled"
+			return m, nil
+		case "ctrl+c":
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	if m.searchMode {
+		return m.handleSearchKey(msg)
+	}
+
+	if m.commenting {
+		return m.handleCommentKey(msg)
+	}
+
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	action := m.config.keyMap.Action(msg.String())
+
+	// The help overlay scrolls with the movement keys; anything else closes it
+	if m.showingHelp {
+		switch action {
+		case actionUp:
+			if m.helpScroll > 0 {
+				m.helpScroll--
+			}
+		case actionDown:
+			if m.helpScroll < m.maxHelpScroll() {
+				m.helpScroll++
+			}
+		default:
+			m.showingHelp = false
+		}
+		return m, nil
+	}
+
+	if m.showingRepoPicker {
+		return m.handleRepoPickerKey(action)
+	}
+
+	if m.showingSnooze {
+		return m.handleSnoozeKey(action)
+	}
+
+	if m.showingRepos {
+		return m.handleReposKey(action)
+	}
+
+	if m.showingOverview {
+		m.renderOverview()
+		switch action {
+		case actionUp:
+			m.overviewViewport.LineUp(1)
+		case actionDown:
+			m.overviewViewport.LineDown(1)
+		case actionPageUp:
+			m.overviewViewport.ViewUp()
+		case actionPageDown:
+			m.overviewViewport.ViewDown()
+		case actionTop:
+			m.overviewViewport.GotoTop()
+		case actionBottom:
+			m.overviewViewport.GotoBottom()
+		case actionOverview, actionSummary, actionBack, actionQuit:
+			m.showingOverview = false
+		}
+		return m, nil
+	}
+
+	if m.showingSummary {
+		// Scrolling goes through the key map rather than the viewport's own
+		// bindings, which would clash with ours and ignore rebinding
+		switch action {
+		case actionUp:
+			m.summaryViewport.LineUp(1)
+			return m, nil
+		case actionDown:
+			m.summaryViewport.LineDown(1)
+			return m, nil
+		case actionPageUp:
+			m.summaryViewport.ViewUp()
+			return m, nil
+		case actionPageDown:
+			m.summaryViewport.ViewDown()
+			return m, nil
+		case actionTop:
+			m.summaryViewport.GotoTop()
+			return m, nil
+		case actionBottom:
+			m.summaryViewport.GotoBottom()
+			return m, nil
+		case actionQuit, actionBack, actionSummary:
+			m.showingSummary = false
+			m.statusMessage = ""
+			return m, nil
+		case actionRefresh:
+			// Bypass the cache, e.g. after new comments
+			notification, ok := m.selectedNotification()
+			if !ok || notification.Subject.URL == "" || m.summaryLoading || m.summaryPlain {
+				return m, nil
+			}
+			m.summaryLoading = true
+			m.statusMessage = "Refreshing summary..."
+			return m, fetchDetailsCmd(m.client, notification.Subject.URL, notification.Subject.Type)
+		}
+		return m, nil
+	}
+
+	// A lone g is the first half of the vim-style gg motion; any other key
+	// abandons it
+	pendingG := m.pendingG
+	m.pendingG = false
+
+	// Unbound digits type a row number to jump to on enter; esc or any other
+	// key abandons it
+	if key := msg.String(); action == "" && len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+		m.jumpBuffer += key
+		m.statusMessage = "Jump to: " + m.jumpBuffer
+		return m, nil
+	}
+	if m.jumpBuffer != "" {
+		buffer := m.jumpBuffer
+		m.jumpBuffer = ""
+		switch action {
+		case actionOpen:
+			return m.jumpTo(buffer)
+		case actionBack:
+			m.statusMessage = "Jump cancelled"
+			return m, nil
+		}
+		m.statusMessage = ""
+	}
+
+	switch action {
+
+	case actionHelp:
+		m.showingHelp = true
+		m.helpScroll = 0
+		return m, nil
+
+	case actionQuit:
+		// Requests in flight would be abandoned along with their results
+		if (m.inFlight > 0 || m.bulkDone < m.bulkTotal) && m.config.Confirmations.QuitWithPending {
+			m.confirmingQuit = true
+			return m.confirm(fmt.Sprintf("Pending actions %s quit anyway? (y/n)", glyphs.dash), tea.Quit)
+		}
+		if len(m.selected) > 0 && m.config.Confirmations.QuitWithSelection {
+			m.confirmingQuit = true
+			return m.confirm(fmt.Sprintf("You have %d selected, quit anyway? (y/n)", len(m.selected)), tea.Quit)
+		}
+		return m, tea.Quit
+
+	case actionUp:
+		if m.selectedIndex > 0 {
+			m.selectedIndex--
+		}
+		return m, nil
+
+	case actionDown:
+		if m.selectedIndex < len(m.visibleNotifications())-1 {
+			m.selectedIndex++
+		}
+		return m, m.loadMoreIfNearEnd()
+
+	case actionPageDown:
+		m.selectedIndex += m.listHeight()
+		m.clampSelection()
+		return m, m.loadMoreIfNearEnd()
+
+	case actionPageUp:
+		m.selectedIndex -= m.listHeight()
+		m.clampSelection()
+		return m, nil
+
+	case actionTop:
+		if msg.String() == "g" && !pendingG {
+			m.pendingG = true
+			return m, nil
+		}
+		m.selectedIndex = 0
+		return m, nil
+
+	case actionBottom:
+		if count := len(m.visibleNotifications()); count > 0 {
+			m.selectedIndex = count - 1
+		}
+		return m, m.loadMoreIfNearEnd()
+
+	case actionOpen:
+		if notification, ok := m.selectedNotification(); ok {
+			return m, openInBrowserCmd(m.client, notification)
+		}
+		return m, nil
+
+	case actionOpenSelected:
+		notifications := m.selectedList()
+		if len(notifications) == 0 {
+			m.statusMessage = "Nothing selected"
+			return m, nil
+		}
+		m.statusMessage = fmt.Sprintf("Opening %d in browser...", len(notifications))
+		if len(notifications) > openSelectedConfirmThreshold && m.config.Confirmations.BulkActions {
+			return m.confirm(fmt.Sprintf("Open %d tabs in the browser? (y/n)", len(notifications)), openSelectedCmd(m.client, notifications))
+		}
+		return m, openSelectedCmd(m.client, notifications)
+
+	case actionOpenReview:
+		if notification, ok := m.selectedNotification(); ok {
+			return m, openReviewInBrowserCmd(m.client, notification)
+		}
+		return m, nil
+
+	case actionOpenRepoList:
+		if notification, ok := m.selectedNotification(); ok {
+			return m, openRepoListCmd(m.client, notification)
+		}
+		return m, nil
+
+	case actionCopyURL:
+		if notification, ok := m.selectedNotification(); ok {
+			return m, copyURLCmd(m.client, notification)
+		}
+		return m, nil
+
+	case actionCopySelectedURLs:
+		if notifications := m.selectedOrCurrent(); len(notifications) > 0 {
+			return m, copyURLsCmd(m.client, notifications)
+		}
+		return m, nil
+
+	case actionCopyNumber:
+		if notification, ok := m.selectedNotification(); ok {
+			return m, copyReferenceCmd(notification)
+		}
+		return m, nil
+
+	case actionMarkRead:
+		if len(m.selected) > 0 {
+			return m.markSelected(false)
+		}
+		if notification, ok := m.selectedNotification(); ok {
+			cmd := m.trackAction(markAsReadCmd(m.client, notification.ID, false, m.dryRun))
+			return m, cmd
+		}
+		return m, nil
+
+	case actionMarkReadKeep:
+		notification, ok := m.selectedNotification()
+		if !ok {
+			return m, nil
+		}
+		if !notification.Unread {
+			m.statusMessage = "Already read"
+			return m, nil
+		}
+		cmd := m.trackAction(markAsReadCmd(m.client, notification.ID, true, m.dryRun))
+		return m, cmd
+
+	case actionSnooze:
+		return m.openSnooze()
+
+	case actionUndo:
+		m.undo()
+		return m, nil
+
+	case actionSelect:
+		if notification, ok := m.selectedNotification(); ok {
+			if m.selected[notification.ID] {
+				delete(m.selected, notification.ID)
+			} else {
+				m.selected[notification.ID] = true
+			}
+		}
+		return m, nil
+
+	case actionInvertSelection:
+		// Invert the selection across the visible rows, leaving anything
+		// hidden by a filter untouched
+		for _, notification := range m.visibleNotifications() {
+			if m.selected[notification.ID] {
+				delete(m.selected, notification.ID)
+			} else {
+				m.selected[notification.ID] = true
+			}
+		}
+		m.statusMessage = "Inverted selection"
+		return m, nil
+
+	case actionOverview:
+		m.showingOverview = true
+		m.overviewViewport.GotoTop()
+		return m, nil
+
+	case actionRepositories:
+		return m.openRepos()
+
+	case actionSearch:
+		m.searchMode = true
+		m.statusMessage = m.searchStatus()
+		return m, nil
+
+	case actionMarkDone:
+		if len(m.selected) > 0 {
+			return m.markSelected(true)
+		}
+		if notification, ok := m.selectedNotification(); ok {
+			cmd := m.trackAction(markAsDoneCmd(m.client, notification.ID, m.dryRun))
+			return m, cmd
+		}
+		return m, nil
+
+	case actionSubscribe:
+		if notification, ok := m.selectedNotification(); ok {
+			cmd := m.trackAction(subscribeCmd(m.client, notification.ID, m.dryRun))
+			return m, cmd
+		}
+		return m, nil
+
+	case actionShowAll:
+		// A fetch in flight would land in the wrong mode
+		if m.fetching {
+			m.statusMessage = "Already refreshing..."
+			return m, nil
+		}
+		if m.rateLimited() {
+			m.statusMessage = m.rateLimitStatus()
+			return m, nil
+		}
+		m.showAll = !m.showAll
+		m.fetching = true
+		m.statusMessage = "Loading the inbox..."
+		if m.showAll {
+			m.statusMessage = "Loading all threads, including read ones..."
+		}
+		return m, tea.Batch(fetchNotificationsCmd(m.client, m.config, m.query()), m.spinner.Tick)
+
+	case actionUnsubscribe:
+		if notification, ok := m.selectedNotification(); ok {
+			cmd := m.trackAction(unsubscribeCmd(m.client, notification.ID, m.dryRun))
+			return m, cmd
+		}
+		return m, nil
+
+	case actionComment:
+		return m.openComment()
+
+	case actionMarkAllRead:
+		notifications := m.visibleNotifications()
+		if len(notifications) == 0 || len(m.bulkPending) > 0 {
+			return m, nil
+		}
+		ids := make([]string, len(notifications))
+		for i, notification := range notifications {
+			ids[i] = notification.ID
+		}
+		start := func() tea.Msg { return bulkMarkStartMsg{ids: ids} }
+		if !m.config.Confirmations.BulkActions {
+			return m, start
+		}
+		return m.confirm(fmt.Sprintf("Mark all %d as read? (y/n)", len(ids)), start)
+
+	case actionSort:
+		// Keep the cursor on the same notification through the re-sort
+		selected, _ := m.selectedNotification()
+		m.sortMode = nextSortMode(m.sortMode)
+		m.selectByID(selected.ID)
+		m.statusMessage = "Sorted by " + m.sortLabel()
+		return m, nil
+
+	case actionToggleDates:
+		m.absoluteDates = !m.absoluteDates
+		return m, nil
+
+	case actionWrap:
+		m.wrapSelected = !m.wrapSelected
+		return m, nil
+
+	case actionDensity:
+		if m.density == densityDetailed {
+			m.density = densityCompact
+			m.statusMessage = "Compact rows"
+		} else {
+			m.density = densityDetailed
+			m.statusMessage = "Detailed rows"
+		}
+		return m, nil
+
+	case actionFilterType:
+		m.typeFilter = nextTypeFilter(m.typeFilter)
+		m.clampSelection()
+		m.statusMessage = m.filterStatus()
+		return m, nil
+
+	case actionFilterAge:
+		m.ageFilter = nextAgeFilter(m.ageFilter)
+		m.clampSelection()
+		m.statusMessage = m.filterStatus()
+		return m, nil
+
+	case actionUnreadOnly:
+		// Stay on the same notification, or its nearest unread neighbour
+		nearby := m.selectionNeighbours()
+		m.unreadOnly = !m.unreadOnly
+		m.selectByID(nearby...)
+		m.statusMessage = m.filterStatus()
+		if m.unreadOnly {
+			m.statusMessage = fmt.Sprintf("Showing unread only (%d of %d)",
+				len(m.visibleNotifications()), len(m.notifications))
+		}
+		return m, nil
+
+	case actionHideBots:
+		nearby := m.selectionNeighbours()
+		m.hideBots = !m.hideBots
+		m.selectByID(nearby...)
+		m.statusMessage = m.filterStatus()
+		return m, nil
+
+	case actionRepoPicker:
+		return m.openRepoPicker()
+
+	case actionFocus:
+		nearby := m.selectionNeighbours()
+		m.toggleFocus()
+		m.selectByID(nearby...)
+		if m.focusMode {
+			m.statusMessage = "Focus: " + m.filterStatus()
+		} else {
+			m.statusMessage = "Focus off, filters restored"
+		}
+		return m, nil
+
+	case actionClearFilters:
+		nearby := m.selectionNeighbours()
+		m.setFilters(filterState{sortMode: m.sortMode})
+		m.focusMode = false
+		m.selectByID(nearby...)
+		m.statusMessage = m.filterStatus()
+		return m, nil
+
+	case actionFilterReason:
+		m.reasonFilter = m.nextReasonFilter()
+		m.clampSelection()
+		m.statusMessage = m.filterStatus()
+		return m, nil
+
+	case actionRefresh:
+		if m.fetching {
+			m.statusMessage = "Already refreshing..."
+			return m, nil
+		}
+		if m.rateLimited() {
+			m.statusMessage = m.rateLimitStatus()
+			return m, nil
+		}
+		m.loading = true
+		m.fetching = true
+		m.statusMessage = "Refreshing notifications..."
+		return m, tea.Batch(fetchNotificationsCmd(m.client, m.config, m.query()), m.spinner.Tick)
+
+	case actionAutoRefresh:
+		m.autoRefresh = !m.autoRefresh
+		m.refreshGen++
+		if !m.autoRefresh {
+			m.statusMessage = "Auto-refresh off"
+			return m, nil
+		}
+		m.statusMessage = fmt.Sprintf("Auto-refresh every %s", m.refreshEvery())
+		return m, autoRefreshTickCmd(m.refreshEvery(), m.refreshGen)
 
+	case actionSummary:
+		if notification, ok := m.selectedNotification(); ok {
+			m.showingSummary = !m.showingSummary
+			if m.showingSummary {
+				m.summaryViewport.GotoTop() // a new summary starts at the top
+				if notification.Subject.URL == "" {
+					// Some notifications (e.g. some releases) have no subject to fetch
+					m.summaryLoading = false
+					m.setSummary(notification, detailsLoadedMsg{body: "_No preview available._"})
+					m.statusMessage = "No preview available"
+				} else if summary, ok := m.detailCache[notification.Subject.URL]; ok {
+					m.summaryLoading = false
+					m.setSummary(notification, summary)
+					m.statusMessage = "Summary loaded from cache"
+				} else {
+					m.summaryLoading = true
+					m.summaryHeader = ""
+					m.summaryBody = "Loading..."
+					return m, fetchDetailsCmd(m.client, notification.Subject.URL, notification.Subject.Type)
+				}
+			} else {
+				m.statusMessage = ""
+			}
+		}
 		return m, nil
 
-	case errorMsg:
-		m.err = error(msg)
-		m.loading = false
-		m.statusMessage = fmt.Sprintf("Error: %v", m.err)
+	case actionRawJSON:
+		if notification, ok := m.selectedNotification(); ok {
+			m.showRawJSON(notification)
+		}
 		return m, nil
 
-	case statusMsg:
-		m.statusMessage = string(msg)
+	case actionBack:
+		if m.showingSummary {
+			m.showingSummary = false
+			m.statusMessage = ""
+			return m, nil
+		}
+		if m.searchQuery != "" {
+			m.searchQuery = ""
+			m.clampSelection()
+			m.statusMessage = "Search cleared"
+			return m, nil
+		}
+		if m.repoFilter != "" {
+			m.repoFilter = ""
+			m.clampSelection()
+			m.statusMessage = "Repository filter cleared"
+			return m, nil
+		}
+	}
+
+	return m, nil
+}
+
+// handleSearchKey edits the search prompt. Every printable key is taken as
+// query text, so navigation keys don't move the selection while typing.
+func (m Model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.searchMode = false
+		m.searchQuery = ""
+		m.clampSelection()
+		m.statusMessage = "Search cleared"
+		return m, nil
+	case tea.KeyEnter:
+		m.searchMode = false
+		m.statusMessage = m.searchStatus()
+		return m, nil
+	case tea.KeyBackspace:
+		if query := []rune(m.searchQuery); len(query) > 0 {
+			m.searchQuery = string(query[:len(query)-1])
+		}
+	case tea.KeySpace:
+		m.searchQuery += " "
+	case tea.KeyRunes:
+		m.searchQuery += string(msg.Runes)
+	default:
 		return m, nil
 	}
 
+	m.clampSelection()
+	m.statusMessage = m.searchStatus()
 	return m, nil
 }
 
-func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+func (m Model) searchStatus() string {
+	if m.searchQuery == "" {
+		return "Type to search titles and repositories"
+	}
+	matches := len(m.visibleNotifications())
+	if matches == 1 {
+		return "1 match"
+	}
+	return fmt.Sprintf("%d matches", matches)
+}
+
+// markSelected marks every selected notification as read, or as done, in
+// batches like mark-all-read
+func (m Model) markSelected(done bool) (tea.Model, tea.Cmd) {
+	if len(m.bulkPending) > 0 {
+		return m, nil
+	}
+	ids := make([]string, 0, len(m.selected))
+	for _, notification := range m.notifications {
+		if m.selected[notification.ID] {
+			ids = append(ids, notification.ID)
+		}
+	}
+	return m, func() tea.Msg { return bulkMarkStartMsg{ids: ids, done: done} }
+}
+
+// trackAction counts cmd as pending until its result message decrements
+// inFlight, turning the spinner meanwhile
+func (m *Model) trackAction(cmd tea.Cmd) tea.Cmd {
+	m.inFlight++
+	return tea.Batch(cmd, m.spinner.Tick)
+}
+
+// pendingActions counts actions sent to GitHub that haven't been answered,
+// including the rest of a bulk mark
+func (m Model) pendingActions() int {
+	return m.inFlight + m.bulkTotal - m.bulkDone
+}
+
+// confirm shows a yes/no prompt and runs cmd if the user answers yes
+func (m Model) confirm(prompt string, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m.confirming = true
+	m.confirmPrompt = prompt
+	m.onConfirm = cmd
+	return m, nil
+}
+
+// The smallest terminal the layout works in; anything smaller only shows a
+// note asking for more room
+const (
+	minTerminalWidth  = 40
+	minTerminalHeight = 10
+)
+
+func (m Model) View() string {
+	if m.terminalWidth < minTerminalWidth || m.terminalHeight < minTerminalHeight {
+		return ansi.Wrap(fmt.Sprintf("Terminal too small %s resize to at least %dx%d",
+			glyphs.dash, minTerminalWidth, minTerminalHeight), max(m.terminalWidth, 1), "")
+	}
+
+	if m.loading {
+		return fmt.Sprintf("\n  %s\n\n  %s %s\n",
+			titleStyle.Render("GitHub Notifications"),
+			m.spinner.View(),
+			m.statusMessage)
+	}
+
+	if m.err != nil {
+		return fmt.Sprintf("\n  %s\n\n  Error: %v\n\n  Press 'q' to quit, 'f' to retry\n",
+			titleStyle.Render("GitHub Notifications"),
+			m.err)
+	}
+
+	if m.commenting {
+		return m.commentView()
+	}
+
+	if m.showingHelp {
+		return m.helpView()
+	}
+
+	if m.showingRepoPicker {
+		return m.repoPickerView()
+	}
+
+	if m.showingSnooze {
+		return m.snoozeView()
+	}
+
+	if m.showingRepos {
+		return m.reposView()
+	}
+
+	if m.showingOverview {
+		return m.overviewView()
+	}
+
 	if m.showingSummary {
-		switch msg.String() {
-		case "up", "k":
-			if m.summaryScroll > 0 {
-				m.summaryScroll--
+		if m.summaryLoading {
+			return summaryBoxStyle.Render("Loading...")
+		}
+
+		var finalContent strings.Builder
+		if !m.summaryViewport.AtTop() {
+			finalContent.WriteString(dimStyle.Render(glyphs.scrollUp))
+		}
+		finalContent.WriteString("\n")
+		finalContent.WriteString(m.summaryViewport.View())
+		finalContent.WriteString("\n")
+		if !m.summaryViewport.AtBottom() {
+			finalContent.WriteString(dimStyle.Render(glyphs.scrollDown))
+		}
+
+		return summaryBoxStyle.Render(finalContent.String()) + "\n" + dimStyle.Render(m.helpLine())
+	}
+
+	var b strings.Builder
+
+	// Title
+	unread, total := m.counts()
+	b.WriteString(titleStyle.Render("GitHub Notifications"))
+	b.WriteString(dimStyle.Render(fmt.Sprintf(" %s %d unread / %d total", glyphs.dash, unread, total)))
+	if m.filtered() {
+		b.WriteString(dimStyle.Render(" (filtered)"))
+	}
+	if m.cached {
+		b.WriteString(dimStyle.Render(" (cached)"))
+	}
+	if m.listedQuery.all {
+		b.WriteString(dimStyle.Render(" (all threads)"))
+	}
+	if m.listedQuery.participating {
+		b.WriteString(dimStyle.Render(" (participating)"))
+	}
+	if m.listedQuery.repo != "" {
+		b.WriteString(dimStyle.Render(" (" + m.listedQuery.repo + " only)"))
+	}
+	if m.dryRun {
+		b.WriteString(warningStyle.Render(" (dry run)"))
+	}
+	b.WriteString("\n\n")
+
+	// Header
+	notifications := m.visibleNotifications()
+	if len(notifications) > 0 {
+		header := fmt.Sprintf("          %-11s %-20s %-10s %-10s %-6s %-2s %s", "Updated", "Repository", "Type", "Reason", "State", "By", "Title")
+		if m.density == densityDetailed {
+			header = "          Title"
+		}
+		b.WriteString(headerStyle.Render(header))
+		b.WriteString("\n")
+
+		// Notifications list
+		startIdx, endIdx := m.listWindow(notifications)
+		for i := startIdx; i < endIdx; i++ {
+			notification := notifications[i]
+			line := m.formatNotificationLine(notification, i)
+			if i == m.selectedIndex {
+				line = "> " + line
+				line = selectedStyle.Render(line)
+			} else {
+				line = "  " + line
 			}
-			return m, nil
-		case "down", "j":
-			viewHeight := m.terminalHeight - 6
-			if viewHeight < 1 {
-				viewHeight = 1
+
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	} else {
+		b.WriteString(m.emptyState())
+	}
+
+	if m.authExpired {
+		b.WriteString("\n")
+		b.WriteString(warningStyle.Render(fmt.Sprintf("Authentication expired %s run gh auth login, then press %s to retry",
+			glyphs.dash, m.config.keyMap.Labels(actionRefresh))))
+		b.WriteString("\n")
+	}
+
+	// Search prompt
+	if m.searchMode {
+		b.WriteString("\n/")
+		b.WriteString(m.searchQuery)
+		b.WriteString(selectedStyle.Render("_"))
+		b.WriteString("\n")
+	}
+
+	// Status line
+	b.WriteString("\n")
+	if m.confirming {
+		b.WriteString(unreadStyle.Render(m.confirmPrompt))
+	} else {
+		if m.fetching || m.pendingActions() > 0 {
+			// Background refresh, or actions awaiting GitHub
+			b.WriteString(m.spinner.View() + " ")
+		}
+		b.WriteString(statusStyle.Render(m.statusLine()))
+	}
+	if pending := m.pendingActions(); pending == 1 {
+		b.WriteString(dimStyle.Render("  (1 action pending)"))
+	} else if pending > 1 {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  (%d actions pending)", pending)))
+	}
+	if len(m.selected) > 0 {
+		b.WriteString("  ")
+		b.WriteString(selectedStyle.Render(fmt.Sprintf("%d selected", len(m.selected))))
+	}
+	if m.autoRefresh {
+		b.WriteString("  ")
+		b.WriteString(dimStyle.Render(fmt.Sprintf("auto-refresh %s, last %s",
+			m.refreshEvery(), m.lastRefresh.Format("15:04:05"))))
+	}
+	if m.rateLimit.Limit > 0 {
+		b.WriteString("  ")
+		quota := fmt.Sprintf("API: %d/%d", m.rateLimit.Remaining, m.rateLimit.Limit)
+		if m.rateLimit.Remaining*10 < m.rateLimit.Limit {
+			b.WriteString(warningStyle.Render(quota))
+		} else {
+			b.WriteString(dimStyle.Render(quota))
+		}
+	}
+	b.WriteString("\n")
+
+	// Help text
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render(m.helpLine()))
+
+	return b.String()
+}
+
+// emptyState explains an empty list, telling apart an inbox hidden by
+// filters from one that is genuinely empty
+func (m Model) emptyState() string {
+	keys := m.config.keyMap
+	if m.filtered() && len(m.notifications) > 0 {
+		return fmt.Sprintf("No notifications match the current filters %s %d hidden\n%s\n",
+			glyphs.dash, len(m.notifications),
+			dimStyle.Render(fmt.Sprintf("Press %s to clear filters", keys.Labels(actionClearFilters))))
+	}
+	return fmt.Sprintf("No notifications found\n%s\n",
+		dimStyle.Render(fmt.Sprintf("Press %s to refresh, %s to quit",
+			keys.Labels(actionRefresh), keys.Labels(actionQuit))))
+}
+
+// countEntry is one row of the overview breakdowns
+type countEntry struct {
+	label string
+	count int
+}
+
+// countBy tallies notifications by key, largest first
+func countBy(notifications []Notification, key func(Notification) string) []countEntry {
+	counts := make(map[string]int)
+	for _, notification := range notifications {
+		counts[key(notification)]++
+	}
+	entries := make([]countEntry, 0, len(counts))
+	for label, count := range counts {
+		entries = append(entries, countEntry{label: label, count: count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].label < entries[j].label
+	})
+	return entries
+}
+
+// overviewView renders counts by reason, type and repository
+func (m Model) overviewView() string {
+	// Laid out afresh, as a refresh or resize may have changed the counts
+	m.renderOverview()
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Inbox Overview"))
+	b.WriteString("\n")
+	if !m.overviewViewport.AtTop() {
+		b.WriteString(dimStyle.Render(glyphs.scrollUp))
+	}
+	b.WriteString("\n")
+	b.WriteString(m.overviewViewport.View())
+	b.WriteString("\n")
+	if !m.overviewViewport.AtBottom() {
+		b.WriteString(dimStyle.Render(glyphs.scrollDown))
+	}
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render(m.helpLine()))
+	return b.String()
+}
+
+// renderOverview lays out the overview's counts in its viewport, keeping the
+// scroll position where it still fits
+func (m *Model) renderOverview() {
+	unread := 0
+	for _, notification := range m.notifications {
+		if notification.Unread {
+			unread++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s of %d notifications\n",
+		unreadStyle.Render(fmt.Sprintf("%d unread", unread)),
+		len(m.notifications)))
+
+	sections := []struct {
+		title   string
+		entries []countEntry
+	}{
+		{"By reason", countBy(m.notifications, func(n Notification) string { return n.ReasonDisplay() })},
+		{"By type", countBy(m.notifications, func(n Notification) string { return n.TypeDisplay() })},
+		{"By repository", countBy(m.notifications, func(n Notification) string { return n.RepoName() })},
+	}
+	for _, section := range sections {
+		b.WriteString("\n")
+		b.WriteString(headerStyle.Render(section.title))
+		b.WriteString("\n")
+		for _, entry := range section.entries {
+			b.WriteString(fmt.Sprintf("  %4d  %s\n", entry.count, entry.label))
+		}
+	}
+
+	content := strings.TrimSuffix(b.String(), "\n")
+	m.overviewViewport.Width = lipgloss.Width(content)
+	m.overviewViewport.Height = min(strings.Count(content, "\n")+1, m.overviewHeight())
+	m.overviewViewport.SetContent(content)
+}
+
+// overviewHeight is how many lines of counts fit below the overview's title
+// and above its help line, leaving room for the scroll indicators
+func (m Model) overviewHeight() int {
+	return max(m.terminalHeight-4, 1)
+}
+
+// modeHelp is the help line while a prompt, the summary pane or the overview
+// has the keyboard, or "" for the list's
+func (m Model) modeHelp() string {
+	keys := m.config.keyMap
+	switch {
+	case m.confirming:
+		return "y:Yes  n:No"
+	case m.searchMode:
+		return "Enter:Apply  Esc:Cancel"
+	case m.jumpBuffer != "":
+		return "Enter:Jump  Esc:Cancel"
+	case m.showingSummary:
+		help := []string{glyphs.arrows + ":Scroll"}
+		for _, item := range []struct{ action, label string }{
+			{actionPageDown, "Page"},
+			{actionRefresh, "Reload"},
+			{actionBack, "Back"},
+		} {
+			// The raw JSON has nothing to reload
+			if item.action == actionRefresh && m.summaryPlain {
+				continue
 			}
-			maxScroll := len(m.summaryLines) - viewHeight
-			if maxScroll < 0 {
-				maxScroll = 0
+			if entry := keys.Help(item.action, item.label); entry != "" {
+				help = append(help, entry)
 			}
-			if m.summaryScroll < maxScroll {
-				m.summaryScroll++
+		}
+		return strings.Join(help, "  ")
+	case m.showingOverview:
+		help := []string{glyphs.arrows + ":Scroll"}
+		for _, entry := range []string{
+			keys.Help(actionPageDown, "Page"),
+			keys.HelpAny("Back", actionOverview, actionSummary, actionBack, actionQuit),
+		} {
+			if entry != "" {
+				help = append(help, entry)
 			}
+		}
+		return strings.Join(help, "  ")
+	}
+	return ""
+}
+
+// helpLine lists the most used keys, as currently bound; the help overlay
+// has the rest
+func (m Model) helpLine() string {
+	if help := m.modeHelp(); help != "" {
+		return help
+	}
+	keys := m.config.keyMap
+	help := []string{glyphs.arrows + ":Navigate"}
+	for _, item := range []struct{ action, label string }{
+		{actionOpen, "Open"},
+		{actionMarkRead, "Mark Read"},
+		{actionMarkDone, "Done"},
+		{actionSelect, "Select"},
+		{actionSearch, "Search"},
+		{actionFilterType, "Type"},
+		{actionFilterReason, "Reason"},
+		{actionRefresh, "Refresh"},
+		{actionSummary, "Summary"},
+		{actionHelp, "Help"},
+		{actionQuit, "Quit"},
+	} {
+		if entry := keys.Help(item.action, item.label); entry != "" {
+			help = append(help, entry)
+		}
+	}
+	return strings.Join(help, "  ")
+}
+
+// listWindow returns the range of notifications on screen, scrolled to keep
+// the cursor near the middle. Rows can be more than one line tall, so the
+// window is filled by height rather than by count.
+func (m Model) listWindow(notifications []Notification) (start, end int) {
+	count := len(notifications)
+	if count == 0 {
+		return 0, 0
+	}
+	height := m.listHeight()
+
+	// Rows above the cursor take up to half the screen, rows below fill the
+	// rest, and any room left near the end of the list goes back above
+	start, end = m.selectedIndex, m.selectedIndex+1
+	above := 0
+	for start > 0 && above+m.rowHeight(notifications, start-1) <= height/2 {
+		start--
+		above += m.rowHeight(notifications, start)
+	}
+	used := above + m.rowHeight(notifications, m.selectedIndex)
+	for end < count && used+m.rowHeight(notifications, end) <= height {
+		used += m.rowHeight(notifications, end)
+		end++
+	}
+	for start > 0 && used+m.rowHeight(notifications, start-1) <= height {
+		start--
+		used += m.rowHeight(notifications, start)
+	}
+	return start, end
+}
+
+// listTop is the screen row of the first notification: below the title, a
+// blank line and the column header
+const listTop = 3
+
+// handleMouse selects the clicked row, opening it if it was already
+// selected, and scrolls with the wheel. Overlays ignore the mouse.
+func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.loading || m.err != nil || m.confirming || m.searchMode || m.commenting ||
+		m.showingHelp || m.showingOverview || m.showingRepoPicker || m.showingSnooze || m.showingRepos || m.showingSummary {
+		return m, nil
+	}
+
+	notifications := m.visibleNotifications()
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		if m.selectedIndex > 0 {
+			m.selectedIndex--
+		}
+		return m, nil
+
+	case msg.Button == tea.MouseButtonWheelDown:
+		if m.selectedIndex < len(notifications)-1 {
+			m.selectedIndex++
+		}
+		return m, m.loadMoreIfNearEnd()
+
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		start, end := m.listWindow(notifications)
+		index := -1
+		for i, y := start, listTop; i < end; i++ {
+			height := m.rowHeight(notifications, i)
+			if msg.Y >= y && msg.Y < y+height {
+				index = i
+				break
+			}
+			y += height
+		}
+		if index < 0 {
 			return m, nil
-		case "q", "ctrl+c", "esc", "tab":
-			m.showingSummary = false
-			m.statusMessage = ""
-			return m, nil
 		}
-		return m, nil
+		if index == m.selectedIndex {
+			return m, openInBrowserCmd(m.client, notifications[index])
+		}
+		m.selectedIndex = index
+		return m, nil
+	}
+	return m, nil
+}
+
+// listHeight is how many notification rows fit on screen
+func (m Model) listHeight() int {
+	height := m.terminalHeight - 8 // Reserve space for header, status, and help
+	if m.searchMode {
+		height -= 2 // search prompt
+	}
+	if m.authExpired {
+		height -= 2 // authentication banner
+	}
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+// Sort modes, cycled in this order
+const (
+	sortNewest = "newest"
+	sortOldest = "oldest"
+	sortRepo   = "repo"
+	sortType   = "type"
+	sortUnread = "unread"
+)
+
+// List densities: one line per notification, or the full title with the
+// other columns beneath
+const (
+	densityCompact  = "compact"
+	densityDetailed = "detailed"
+)
+
+var sortModes = []string{sortNewest, sortOldest, sortRepo, sortType, sortUnread}
+
+func nextSortMode(current string) string {
+	for i, mode := range sortModes {
+		if mode == current && i+1 < len(sortModes) {
+			return sortModes[i+1]
+		}
+	}
+	return sortModes[0]
+}
+
+// sortNotifications orders notifications in place. Ties keep their
+// newest-first order from the API.
+func sortNotifications(notifications []Notification, mode string) {
+	var less func(a, b Notification) bool
+	switch mode {
+	case sortOldest:
+		less = func(a, b Notification) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	case sortRepo:
+		less = func(a, b Notification) bool {
+			return strings.ToLower(a.RepoName()) < strings.ToLower(b.RepoName())
+		}
+	case sortType:
+		less = func(a, b Notification) bool { return a.TypeDisplay() < b.TypeDisplay() }
+	case sortUnread:
+		less = func(a, b Notification) bool {
+			if a.Unread != b.Unread {
+				return a.Unread
+			}
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+	default:
+		less = func(a, b Notification) bool { return a.UpdatedAt.After(b.UpdatedAt) }
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return less(notifications[i], notifications[j])
+	})
+}
+
+// removeNotification drops a notification from the list, keeping the
+// selection valid
+func (m *Model) removeNotification(id string) {
+	current, _ := m.selectedNotification()
+	for i, notification := range m.notifications {
+		if notification.ID == id {
+			m.notifications = append(m.notifications[:i], m.notifications[i+1:]...)
+			delete(m.selected, id)
+			break
+		}
 	}
 
-	switch msg.String() {
+	// If the cursor moved on while the request was in flight, stay with it
+	if current.ID != id {
+		m.selectByID(current.ID)
+		return
+	}
+	// Otherwise the next row has shifted up under the cursor, which is
+	// where cursor_after_mark: next wants it
+	if m.config.CursorAfterMark == cursorPrevious && m.selectedIndex > 0 {
+		m.selectedIndex--
+	}
+	m.clampSelection()
+}
 
-	case "q", "ctrl+c":
-		return m, tea.Quit
+// fetchStaleSubjectsCmd fetches details for subjects whose state is stale,
+// or returns nil if there are none
+func (m *Model) fetchStaleSubjectsCmd() tea.Cmd {
+	stale := m.staleSubjectStates()
+	if len(stale) == 0 {
+		return nil
+	}
+	// Mark them as fetched up front, keeping any previous state for now, so
+	// a refresh mid-stream doesn't fetch them again
+	for _, notification := range stale {
+		known := m.subjectStates[notification.Subject.URL]
+		known.asOf = notification.UpdatedAt
+		m.subjectStates[notification.Subject.URL] = known
+	}
+	return fetchSubjectDetailsCmd(m.client, stale, m.config.UseGraphQL)
+}
 
-	case "up", "k":
-		if m.selectedIndex > 0 {
-			m.selectedIndex--
-		}
-		return m, nil
+// loadMoreThreshold is how close to the end of the list the cursor gets
+// before infinite scroll fetches the next page
+const loadMoreThreshold = 5
 
-	case "down", "j":
-		if m.selectedIndex < len(m.notifications)-1 {
-			m.selectedIndex++
-		}
-		return m, nil
+// loadMoreIfNearEnd fetches the next page when the cursor nears the end of
+// the list and the server has more
+func (m *Model) loadMoreIfNearEnd() tea.Cmd {
+	if !m.hasMore || m.pageLoading || m.fetching || m.rateLimited() {
+		return nil
+	}
+	if m.selectedIndex < len(m.visibleNotifications())-loadMoreThreshold {
+		return nil
+	}
+	m.pageLoading = true
+	m.statusMessage = "Loading more..."
+	return fetchPageCmd(m.client, m.config.PostFetchCmd, m.listedQuery, m.page+1, m.pageGen)
+}
 
-	case "enter":
-		if len(m.notifications) > 0 {
-			notification := m.notifications[m.selectedIndex]
-			return m, openInBrowserCmd(notification)
+// staleSubjectStates returns the issues and pull requests whose state hasn't
+// been fetched, or was fetched before their latest update
+func (m Model) staleSubjectStates() []Notification {
+	var stale []Notification
+	for _, notification := range m.notifications {
+		if notification.Subject.URL == "" {
+			continue
 		}
-		return m, nil
+		if notification.Subject.Type != "Issue" && notification.Subject.Type != "PullRequest" {
+			continue
+		}
+		if known, ok := m.subjectStates[notification.Subject.URL]; ok && !notification.UpdatedAt.After(known.asOf) {
+			continue
+		}
+		stale = append(stale, notification)
+	}
+	return stale
+}
 
-	case "r":
-		if len(m.notifications) > 0 {
-			notification := m.notifications[m.selectedIndex]
-			return m, markAsReadCmd(notification.ID)
+// pushUndo records a notification about to be removed as read
+func (m *Model) pushUndo(id string) {
+	for i, notification := range m.notifications {
+		if notification.ID == id {
+			m.undoStack = append(m.undoStack, undoEntry{notification: notification, index: i})
+			if len(m.undoStack) > maxUndo {
+				m.undoStack = m.undoStack[1:]
+			}
+			return
 		}
-		return m, nil
+	}
+}
 
-	case "f", "F5":
-		m.loading = true
-		m.statusMessage = "Refreshing notifications..."
-		return m, fetchNotificationsCmd()
+// undo puts the most recently marked-read notification back where it was.
+// GitHub has no API to mark a thread unread, so this only restores it locally,
+// as read, until the next refresh.
+func (m *Model) undo() {
+	if len(m.undoStack) == 0 {
+		m.statusMessage = "Nothing to undo"
+		return
+	}
+	entry := m.undoStack[len(m.undoStack)-1]
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
 
-	case "tab":
-		if len(m.notifications) > 0 {
-			m.showingSummary = !m.showingSummary
-			if m.showingSummary {
-				notification := m.notifications[m.selectedIndex]
-				m.summaryScroll = 0 // Reset scroll on new summary
-				if summary, ok := m.summaryCache[notification.ID]; ok {
-					m.summaryLoading = false
-					author := summary.author
-					if author != "" {
-						author = fmt.Sprintf("by @%s", author)
-					}
-					m.summaryHeader = fmt.Sprintf("Repository: %s\nReason: %s\nType: %s %s\n\n%s",
-						notification.RepoName(),
-						notification.Reason,
-						notification.TypeDisplay(),
-						author,
-						notification.Subject.Title)
-					m.summaryBody = summary.body
-					m.statusMessage = "Summary loaded from cache"
+	entry.notification.Unread = false
+	index := entry.index
+	if index > len(m.notifications) {
+		index = len(m.notifications)
+	}
+	m.notifications = append(m.notifications[:index],
+		append([]Notification{entry.notification}, m.notifications[index:]...)...)
+	m.readCount--
+	m.selectByID(entry.notification.ID)
+	m.statusMessage = "Undid mark as read (GitHub keeps it read; it goes on the next refresh)"
+}
 
-					// Render markdown and set lines
-					renderedBody, err := renderMarkdown(m.summaryBody, m.terminalWidth-8)
-					if err != nil {
-						renderedBody = m.summaryBody // fallback
-					}
-					fullContent := m.summaryHeader + "\n\n---\n\n" + renderedBody
-					m.summaryLines = strings.Split(fullContent, "\n")
-				} else {
-					m.summaryLoading = true
-					m.summaryHeader = ""
-					m.summaryBody = "Loading..."
-					m.summaryLines = []string{}
-					return m, fetchDetailsCmd(notification.Subject.URL, notification.Subject.Type)
-				}
-			} else {
-				m.statusMessage = ""
+// nextBulkMarkCmd takes the next batch off bulkPending
+func (m *Model) nextBulkMarkCmd() tea.Cmd {
+	batch := m.bulkPending
+	if len(batch) > bulkMarkBatchSize {
+		batch = batch[:bulkMarkBatchSize]
+	}
+	m.bulkPending = m.bulkPending[len(batch):]
+	return markBatchCmd(m.client, batch, m.bulkMarkDone, m.dryRun)
+}
+
+// selectByID moves the selection to the first of ids that is visible,
+// clamping to a valid row if none are
+func (m *Model) selectByID(ids ...string) {
+	visible := m.visibleNotifications()
+	for _, id := range ids {
+		for i, notification := range visible {
+			if notification.ID == id {
+				m.selectedIndex = i
+				return
 			}
 		}
-		return m, nil
+	}
+	m.clampSelection()
+}
 
-	case "esc":
-		if m.showingSummary {
-			m.showingSummary = false
-			m.statusMessage = ""
-			return m, nil
+// selectionNeighbours lists the visible IDs ordered by distance from the
+// selection: the selected item, then the ones below and above it, and so on
+func (m Model) selectionNeighbours() []string {
+	visible := m.visibleNotifications()
+	ids := make([]string, 0, len(visible))
+	for offset := 0; len(ids) < len(visible); offset++ {
+		if below := m.selectedIndex + offset; below < len(visible) {
+			ids = append(ids, visible[below].ID)
+		}
+		if above := m.selectedIndex - offset; offset > 0 && above >= 0 {
+			ids = append(ids, visible[above].ID)
 		}
 	}
-
-	return m, nil
+	return ids
 }
 
-func (m Model) View() string {
-	if m.loading {
-		return fmt.Sprintf("\n  %s\n\n  %s\n",
-			titleStyle.Render("GitHub Notifications"),
-			"Loading notifications...")
+// selectedList returns the multi-selected notifications, in the order
+// shown. Selections hidden by a filter are kept.
+func (m Model) selectedList() []Notification {
+	var notifications []Notification
+	for _, notification := range m.notifications {
+		if m.selected[notification.ID] {
+			notifications = append(notifications, notification)
+		}
 	}
+	sortNotifications(notifications, m.sortMode)
+	return notifications
+}
 
-	if m.err != nil {
-		return fmt.Sprintf("\n  %s\n\n  Error: %v\n\n  Press 'q' to quit, 'f' to retry\n",
-			titleStyle.Render("GitHub Notifications"),
-			m.err)
+// selectedOrCurrent returns the multi-selected notifications, or else the one
+// under the cursor
+func (m Model) selectedOrCurrent() []Notification {
+	if notifications := m.selectedList(); len(notifications) > 0 {
+		return notifications
 	}
+	if notification, ok := m.selectedNotification(); ok {
+		return []Notification{notification}
+	}
+	return nil
+}
 
-	if m.showingSummary {
-		if m.summaryLoading {
-			return summaryBoxStyle.Render("Loading...")
+// pruneSelected drops selections for notifications no longer in the list
+func (m *Model) pruneSelected() {
+	present := make(map[string]bool, len(m.notifications))
+	for _, notification := range m.notifications {
+		present[notification.ID] = true
+	}
+	for id := range m.selected {
+		if !present[id] {
+			delete(m.selected, id)
 		}
+	}
+}
 
-		lines := m.summaryLines
-		viewHeight := m.terminalHeight - 6 // Account for padding, borders, and indicators
-		if viewHeight < 1 {
-			viewHeight = 1
-		}
+// selectedNotification returns the highlighted notification, if any
+func (m Model) selectedNotification() (Notification, bool) {
+	visible := m.visibleNotifications()
+	if m.selectedIndex < 0 || m.selectedIndex >= len(visible) {
+		return Notification{}, false
+	}
+	return visible[m.selectedIndex], true
+}
 
-		start := m.summaryScroll
-		end := m.summaryScroll + viewHeight
+// nextReasonFilter cycles from all reasons through each reason present in
+// the current list, in reasonOrder, then back to all
+func (m Model) nextReasonFilter() string {
+	present := make(map[string]bool)
+	for _, notification := range m.notifications {
+		present[notification.Reason] = true
+	}
 
-		if start < 0 {
-			start = 0
+	var reasons []string
+	for _, reason := range reasonOrder {
+		if present[reason] {
+			reasons = append(reasons, reason)
+			delete(present, reason)
 		}
+	}
+	var others []string
+	for reason := range present {
+		others = append(others, reason)
+	}
+	sort.Strings(others)
+	reasons = append(reasons, others...)
 
-		if end > len(lines) {
-			end = len(lines)
+	if m.reasonFilter == "" {
+		if len(reasons) > 0 {
+			return reasons[0]
 		}
-
-		// Ensure we don't scroll past the end
-		if m.summaryScroll > len(lines)-viewHeight && len(lines) > viewHeight {
-			m.summaryScroll = len(lines) - viewHeight
-			start = m.summaryScroll
-			end = start + viewHeight
+		return ""
+	}
+	for i, reason := range reasons {
+		if reason == m.reasonFilter && i+1 < len(reasons) {
+			return reasons[i+1]
 		}
+	}
+	return ""
+}
 
-		visibleContent := strings.Join(lines[start:end], "\n")
+// typeFilters is the cycle order of the type filter, starting from all
+var typeFilters = []string{"", "pr", "issue", "release", "discuss"}
 
-		var finalContent strings.Builder
-		if m.summaryScroll > 0 {
-			finalContent.WriteString(dimStyle.Render("▲") + "\n")
-		} else {
-			finalContent.WriteString("\n")
+func nextTypeFilter(current string) string {
+	for i, typeFilter := range typeFilters {
+		if typeFilter == current && i+1 < len(typeFilters) {
+			return typeFilters[i+1]
 		}
+	}
+	return ""
+}
 
-		finalContent.WriteString(visibleContent)
+// ageFilters is the cycle order of the age filter, starting from all: newer
+// than a day or a week, then older than a week or a month
+var ageFilters = []string{"", "<1d", "<1w", ">1w", ">1m"}
 
-		if m.summaryScroll+viewHeight < len(lines) {
-			finalContent.WriteString("\n" + dimStyle.Render("▼"))
-		} else {
-			finalContent.WriteString("\n")
+func nextAgeFilter(current string) string {
+	for i, ageFilter := range ageFilters {
+		if ageFilter == current && i+1 < len(ageFilters) {
+			return ageFilters[i+1]
 		}
-
-		return summaryBoxStyle.Render(finalContent.String())
 	}
+	return ""
+}
 
-	var b strings.Builder
-
-	// Title
-	b.WriteString(titleStyle.Render("GitHub Notifications"))
-	b.WriteString("\n\n")
-
-	// Header
-	if len(m.notifications) > 0 {
-		header := fmt.Sprintf("      %-20s %-10s %s", "Repository", "Type", "Title")
-		b.WriteString(headerStyle.Render(header))
-		b.WriteString("\n")
+// ageMatches reports whether a notification last updated at updated falls in
+// the age bucket filter
+func ageMatches(filter string, updated, now time.Time) bool {
+	age := now.Sub(updated)
+	switch filter {
+	case "<1d":
+		return age < 24*time.Hour
+	case "<1w":
+		return age < 7*24*time.Hour
+	case ">1w":
+		return age > 7*24*time.Hour
+	case ">1m":
+		return age > 30*24*time.Hour
+	}
+	return true
+}
 
-		// Notifications list
-		visibleHeight := m.terminalHeight - 8 // Reserve space for header, status, and help
-		startIdx := 0
-		endIdx := len(m.notifications)
-
-		// Adjust visible range if list is longer than screen
-		if len(m.notifications) > visibleHeight {
-			startIdx = m.selectedIndex - visibleHeight/2
-			if startIdx < 0 {
-				startIdx = 0
-			}
-			endIdx = startIdx + visibleHeight
-			if endIdx > len(m.notifications) {
-				endIdx = len(m.notifications)
-				startIdx = endIdx - visibleHeight
-				if startIdx < 0 {
-					startIdx = 0
-				}
-			}
-		}
+// rateLimited reports whether GitHub has asked us to hold off fetching
+func (m Model) rateLimited() bool {
+	return time.Now().Before(m.rateLimitReset)
+}
 
-		for i := startIdx; i < endIdx; i++ {
-			notification := m.notifications[i]
-			line := m.formatNotificationLine(notification, i)
-			if i == m.selectedIndex {
-				line = "> " + line
-				line = selectedStyle.Render(line)
-			} else {
-				line = "  " + line
-			}
+func (m Model) rateLimitStatus() string {
+	return fmt.Sprintf("Rate limited; retry at %s", m.rateLimitReset.Format("15:04"))
+}
 
-			b.WriteString(line)
-			b.WriteString("\n")
+// counts returns the unread and total counts of the visible list
+func (m Model) counts() (unread, total int) {
+	notifications := m.visibleNotifications()
+	for _, notification := range notifications {
+		if notification.Unread {
+			unread++
 		}
-	} else {
-		b.WriteString("No notifications found\n")
 	}
+	return unread, len(notifications)
+}
 
-	// Status line
-	b.WriteString("\n")
-	b.WriteString(statusStyle.Render(m.statusMessage))
-	b.WriteString("\n")
-
-	// Help text
-	b.WriteString("\n")
-	help := "↑↓:Navigate  Enter:Open  r:Mark Read  f:Refresh  Tab:Summary  q:Quit"
-	b.WriteString(dimStyle.Render(help))
+// sortLabel describes the list order for the status line
+func (m Model) sortLabel() string {
+	switch m.sortMode {
+	case sortOldest:
+		return "oldest first"
+	case sortRepo:
+		return "repository"
+	case sortType:
+		return "type"
+	case sortUnread:
+		return "unread first"
+	default:
+		return "newest first"
+	}
+}
 
-	return b.String()
+// statusLine renders the configured status_format template
+func (m Model) statusLine() string {
+	unread, total := m.counts()
+	lastRefresh := "never"
+	if !m.lastRefresh.IsZero() {
+		lastRefresh = m.lastRefresh.Format("15:04:05")
+	}
+	return renderStatusFormat(m.config.StatusFormat, map[string]string{
+		"status":       m.statusMessage,
+		"unread":       fmt.Sprintf("%d", unread),
+		"total":        fmt.Sprintf("%d", total),
+		"filter":       m.filterLabel(),
+		"last_refresh": lastRefresh,
+		"sort":         m.sortLabel(),
+	})
 }
 
 func (m Model) formatNotificationLine(notification Notification, index int) string {
-	// Truncate long titles to fit terminal
-	maxTitleLen := m.terminalWidth - 45 // Reserve space for other columns
-	if maxTitleLen < 20 {
-		maxTitleLen = 20
+	if m.density == densityDetailed {
+		return m.formatDetailedLine(notification, index)
 	}
 
+	// Truncate long titles to fit terminal
+	maxTitleLen := m.titleWidth()
+
+	// The selected row can show its full title, wrapped onto extra lines
 	title := notification.Subject.Title
-	if len(title) > maxTitleLen {
-		title = title[:maxTitleLen-3] + "..."
+	var wrapped []string
+	if m.wrapSelected && index == m.selectedIndex {
+		wrapped = m.wrapTitle(title)
+		title = wrapped[0]
+	} else {
+		title = truncate(title, maxTitleLen)
 	}
 
-	// Truncate repository name if too long
-	repo := notification.RepoName()
-	if len(repo) > 20 {
-		repo = repo[:17] + "..."
+	for i := range wrapped {
+		wrapped[i] = m.highlightSearch(wrapped[i])
 	}
+	title = m.highlightSearch(title)
+
+	// Truncate repository name if too long
+	repo := padRight(m.highlightSearch(truncate(notification.RepoName(), 20)), 20)
 
 	// Status icon with color
 	var statusIcon string
@@ -657,25 +3493,359 @@ func (m Model) formatNotificationLine(notification Notification, index int) stri
 		statusIcon = readStyle.Render(notification.StatusIcon())
 	}
 
-	return fmt.Sprintf("%2d %s %-20s %-10s %s",
+	// Multi-select marker
+	marker := " "
+	if m.selected[notification.ID] {
+		marker = selectedStyle.Render(glyphs.marker)
+	}
+
+	// Pad before styling, since the escape codes would throw off %-10s
+	reason := fmt.Sprintf("%-10s", reasonAbbrev(notification.Reason))
+	if m.important(notification) {
+		reason = importantStyle.Render(strings.ToUpper(reason))
+	} else if style, ok := reasonStyles[notification.Reason]; ok {
+		reason = style.Render(reason)
+	}
+
+	// Blank while loading, or for subjects without a state
+	known := m.subjectStates[notification.Subject.URL]
+	state := fmt.Sprintf("%-6s", known.state)
+	if style, ok := stateStyles[known.state]; ok {
+		state = style.Render(state)
+	}
+
+	date := notification.RelativeDate()
+	if m.absoluteDates {
+		date = notification.FormattedDate()
+	}
+
+	// Blank until the subject's details arrive
+	initials := m.authorStyle(known.author).Render(fmt.Sprintf("%-2s", authorInitials(known.author)))
+
+	line := fmt.Sprintf("%2d %s %s %s %-11s %s %-10s %s %s %s ",
 		index+1,
+		marker,
 		statusIcon,
+		m.importantFlag(notification),
+		date,
 		repo,
 		notification.TypeDisplay(),
-		title)
+		reason,
+		state,
+		initials)
+	if len(wrapped) > 1 {
+		// Continuation lines line up with the title column, allowing for
+		// the cursor View puts in front of the row
+		indent := strings.Repeat(" ", lipgloss.Width(line)+2)
+		return line + strings.Join(wrapped, "\n"+indent)
+	}
+	return line + title
+}
+
+// highlightSearch styles every case-insensitive match of the search query in
+// s, so it's clear why a row matched. s must be plain text, since it's
+// searched as is.
+func (m Model) highlightSearch(s string) string {
+	if m.searchQuery == "" {
+		return s
+	}
+	query := strings.ToLower(m.searchQuery)
+	lower := strings.ToLower(s)
+	// Lowercasing a few characters changes their length, which would throw
+	// off the offsets
+	if len(lower) != len(s) {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, query)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		b.WriteString(matchStyle.Render(s[i : i+len(query)]))
+		s, lower = s[i+len(query):], lower[i+len(query):]
+	}
+}
+
+// important reports whether the notification's reason is one of the
+// configured important_reasons
+func (m Model) important(notification Notification) bool {
+	return slices.Contains(m.config.ImportantReasons, notification.Reason)
+}
+
+// importantFlag is the column flagging important notifications, blank for
+// the rest
+func (m Model) importantFlag(notification Notification) string {
+	if m.important(notification) {
+		return importantStyle.Render(glyphs.important)
+	}
+	return " "
+}
+
+// truncate shortens s to at most n terminal columns, ending it with "..." if
+// it was cut. It counts display width, so wide characters such as CJK take
+// two columns, and never splits a character.
+func truncate(s string, n int) string {
+	if ansi.StringWidth(s) <= n {
+		return s
+	}
+	if n <= 3 {
+		return ansi.Truncate(s, max(n, 0), "")
+	}
+	return ansi.Truncate(s, n, "...")
+}
+
+// padRight pads s with spaces to width terminal columns; fmt's %-20s counts
+// runes, which misaligns wide characters
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-ansi.StringWidth(s), 0))
+}
+
+// authorInitials abbreviates a login to two letters for the list, taking
+// the first letter of each of the first two words of logins like
+// "octo-cat", and ignoring the "[bot]" suffix of app accounts
+func authorInitials(login string) string {
+	login = strings.TrimSuffix(login, "[bot]")
+	words := strings.FieldsFunc(login, func(r rune) bool { return r == '-' || r == '_' })
+	var initials []rune
+	if len(words) >= 2 {
+		initials = []rune{[]rune(words[0])[0], []rune(words[1])[0]}
+	} else {
+		initials = []rune(login)
+		if len(initials) > 2 {
+			initials = initials[:2]
+		}
+	}
+	return strings.ToUpper(string(initials))
+}
+
+// authorStyle dims the viewer's own activity, which is usually noise
+func (m Model) authorStyle(author string) lipgloss.Style {
+	if author != "" && strings.EqualFold(author, m.viewer) {
+		return dimStyle
+	}
+	return lipgloss.NewStyle()
+}
+
+// titleWidth is the width of the title column
+func (m Model) titleWidth() int {
+	width := m.terminalWidth - 80 // Reserve space for other columns
+	if width < 20 {
+		width = 20
+	}
+	return width
+}
+
+// wrapTitle splits a title into lines that fit the title column
+func (m Model) wrapTitle(title string) []string {
+	return strings.Split(ansi.Wrap(title, m.titleWidth(), ""), "\n")
+}
+
+// formatDetailedLine renders a row as its full title, wrapped, with the
+// other columns on a line beneath
+func (m Model) formatDetailedLine(notification Notification, index int) string {
+	statusIcon := readStyle.Render(notification.StatusIcon())
+	if notification.Unread {
+		statusIcon = unreadStyle.Render(notification.StatusIcon())
+	}
+	marker := " "
+	if m.selected[notification.ID] {
+		marker = selectedStyle.Render(glyphs.marker)
+	}
+
+	date := notification.RelativeDate()
+	if m.absoluteDates {
+		date = notification.FormattedDate()
+	}
+	details := []string{date, m.highlightSearch(notification.RepoName()), notification.TypeDisplay()}
+
+	reason := reasonAbbrev(notification.Reason)
+	if m.important(notification) {
+		reason = importantStyle.Render(strings.ToUpper(reason))
+	} else if style, ok := reasonStyles[notification.Reason]; ok {
+		reason = style.Render(reason)
+	}
+	details = append(details, reason)
+
+	known := m.subjectStates[notification.Subject.URL]
+	if known.state != "" {
+		state := known.state
+		if style, ok := stateStyles[known.state]; ok {
+			state = style.Render(state)
+		}
+		details = append(details, state)
+	}
+	if known.author != "" {
+		details = append(details, m.authorStyle(known.author).Render("by @"+known.author))
+	}
+
+	line := fmt.Sprintf("%2d %s %s %s ", index+1, marker, statusIcon, m.importantFlag(notification))
+	// Allowing for the cursor View puts in front of the row
+	indent := strings.Repeat(" ", lipgloss.Width(line)+2)
+	lines := m.wrapDetailedTitle(notification.Subject.Title)
+	for i := range lines {
+		lines[i] = m.highlightSearch(lines[i])
+	}
+	return line + strings.Join(lines, "\n"+indent) + "\n" + indent + strings.Join(details, "  ")
+}
+
+// detailedTitleWidth is the width titles wrap to in detailed rows, after the
+// cursor, number, marker, icon and flag
+func (m Model) detailedTitleWidth() int {
+	return max(m.terminalWidth-11, 20)
+}
+
+// wrapDetailedTitle splits a title into lines for a detailed row
+func (m Model) wrapDetailedTitle(title string) []string {
+	return strings.Split(ansi.Wrap(title, m.detailedTitleWidth(), ""), "\n")
+}
+
+// rowHeight is how many screen lines the row at index takes up
+func (m Model) rowHeight(notifications []Notification, index int) int {
+	if m.density == densityDetailed && index < len(notifications) {
+		return len(m.wrapDetailedTitle(notifications[index].Subject.Title)) + 1
+	}
+	if m.wrapSelected && index == m.selectedIndex && index < len(notifications) {
+		return len(m.wrapTitle(notifications[index].Subject.Title))
+	}
+	return 1
+}
+
+// triageSummary describes what was done this session, e.g.
+// "Triaged 23 notifications in 4m12s (15 read, 5 done, 3 opened)".
+// It returns an empty string if nothing was triaged.
+func (m Model) triageSummary() string {
+	total := m.readCount + m.doneCount + m.openedCount
+	if total == 0 {
+		return ""
+	}
+	noun := "notifications"
+	if total == 1 {
+		noun = "notification"
+	}
+	elapsed := time.Since(m.sessionStart).Round(time.Second)
+	return fmt.Sprintf("Triaged %d %s in %s (%d read, %d done, %d opened)",
+		total, noun, elapsed, m.readCount, m.doneCount, m.openedCount)
 }
 
 func main() {
+	themeFlag := flag.String("theme", "", "color theme: auto, dark or light (overrides the config)")
+	listFlag := flag.Bool("list", false, "print notifications and exit instead of starting the TUI")
+	jsonFlag := flag.Bool("json", false, "with --list, print JSON instead of a table")
+	unreadOnlyFlag := flag.Bool("unread-only", false, "with --list, only print unread notifications")
+	yesFlag := flag.Bool("yes", false, "skip every yes/no prompt (overrides the config's confirmations)")
+	participatingFlag := flag.Bool("participating", false, "only fetch threads you're directly participating in (also set by the config)")
+	allFlag := flag.Bool("all", false, "include read notifications, as N does")
+	repoFlag := flag.String("repo", "", "only fetch notifications for this repository, as owner/name")
+	versionFlag := flag.Bool("version", false, "print the version and exit")
+	debugFlag := flag.Bool("debug", false, "log gh commands and errors to ~/.cache/ghn/ghn.log")
+	dryRunFlag := flag.Bool("dry-run", false, "show marks, unsubscribes and comments without sending them to GitHub")
+	hostFlag := flag.String("host", "", "GitHub host, e.g. for GitHub Enterprise Server (default $GH_HOST or github.com)")
+	noColorFlag := flag.Bool("no-color", false, "disable colors and text styles (also set by NO_COLOR)")
+	timeoutFlag := flag.Duration("timeout", 0, "give up on a gh call after this long, e.g. 30s (overrides the config; default 15s)")
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Println(versionString())
+		return
+	}
+
+	if *hostFlag != "" {
+		ghHost = *hostFlag
+	} else if host := os.Getenv("GH_HOST"); host != "" {
+		ghHost = host
+	}
+
+	logFile, err := startDebugLog(*debugFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open debug log: %v\n", err)
+		os.Exit(1)
+	}
+	defer logFile.Close()
+
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *yesFlag {
+		config.Confirmations = Confirmations{} // every prompt off
+	}
+	if *participatingFlag {
+		config.Participating = true
+	}
+	if *repoFlag != "" {
+		if err := validateRepoName(*repoFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	ghTimeout = config.Timeout
+	if *timeoutFlag > 0 {
+		ghTimeout = *timeoutFlag
+	}
+
 	// Check if gh CLI is available
 	if err := checkGitHubCLI(); err != nil {
-		fmt.Printf("Error: %v\n", err)
-		fmt.Println("Please install GitHub CLI: https://cli.github.com/")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Please install GitHub CLI: https://cli.github.com/")
+		os.Exit(1)
+	}
+
+	query := notificationsQuery{all: *allFlag, participating: config.Participating, repo: *repoFlag}
+	if *listFlag {
+		if err := runList(os.Stdout, ghClient{}, config, query, *jsonFlag, *unreadOnlyFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *noColorFlag {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	themeName := config.Theme
+	if *themeFlag != "" {
+		themeName = *themeFlag
+	}
+	theme, err := themeByName(themeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	applyTheme(theme)
+
+	if !supportsUnicode() {
+		usePlainRendering()
+	}
+
+	beeep.AppName = "ghn"
 
 	// Create and run the Bubble Tea program
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		log.Fatal(err)
+	options := []tea.ProgramOption{tea.WithAltScreen()}
+	if config.Mouse {
+		options = append(options, tea.WithMouseCellMotion())
+	}
+	model := initialModel(config, ghClient{}, query)
+	model.dryRun = *dryRunFlag
+	p := tea.NewProgram(model, options...)
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The alt-screen has been torn down by now, so this lands in the
+	// regular terminal scrollback
+	if m, ok := finalModel.(Model); ok {
+		if summary := m.triageSummary(); summary != "" {
+			fmt.Println(summary)
+		}
 	}
 }
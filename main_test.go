@@ -0,0 +1,530 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+func TestExtractIssueNumber(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://api.github.com/repos/owner/repo/issues/42", "42"},
+		{"https://api.github.com/repos/owner/repo/pulls/7", "7"},
+		{"https://api.github.com/repos/owner/repo/pulls/7/", "7"},
+		{"", ""},
+		{"/", ""},
+		{"42", "42"},
+	}
+	for _, tt := range tests {
+		if got := extractIssueNumber(tt.url); got != tt.want {
+			t.Errorf("extractIssueNumber(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestIssueReference(t *testing.T) {
+	tests := []struct {
+		subjectType string
+		url         string
+		want        string
+		ok          bool
+	}{
+		{"Issue", "https://api.github.com/repos/owner/repo/issues/123", "#123", true},
+		{"PullRequest", "https://api.github.com/repos/owner/repo/pulls/456", "#456", true},
+		{"Release", "https://api.github.com/repos/owner/repo/releases/789", "", false},
+		{"Discussion", "", "", false},
+	}
+	for _, tt := range tests {
+		n := Notification{}
+		n.Subject.Type = tt.subjectType
+		n.Subject.URL = tt.url
+		got, ok := issueReference(n)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("issueReference(%s %q) = %q, %v, want %q, %v", tt.subjectType, tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRepoListArgs(t *testing.T) {
+	tests := []struct {
+		subjectType string
+		want        string
+	}{
+		{"Issue", "issue list -R cli/cli --web"},
+		{"PullRequest", "pr list -R cli/cli --web"},
+		{"Release", "repo view cli/cli --web"},
+	}
+	for _, tt := range tests {
+		notification := Notification{Subject: Subject{Type: tt.subjectType}, Repository: Repository{FullName: "cli/cli"}}
+		if got := strings.Join(repoListArgs(notification), " "); got != tt.want {
+			t.Errorf("repoListArgs(%s) = %q, want %q", tt.subjectType, got, tt.want)
+		}
+	}
+}
+
+func TestTypeDisplay(t *testing.T) {
+	tests := []struct {
+		subjectType string
+		want        string
+	}{
+		{"PullRequest", "pr"},
+		{"Issue", "issue"},
+		{"Release", "release"},
+		{"Discussion", "discuss"},
+		{"Commit", "other"},
+		{"CheckSuite", "other"},
+		{"", "other"},
+	}
+	for _, tt := range tests {
+		n := Notification{Subject: Subject{Type: tt.subjectType}}
+		if got := n.TypeDisplay(); got != tt.want {
+			t.Errorf("TypeDisplay() for %q = %q, want %q", tt.subjectType, got, tt.want)
+		}
+	}
+}
+
+func TestStatusIcon(t *testing.T) {
+	unread := Notification{Unread: true}
+	read := Notification{}
+	if got := unread.StatusIcon(); got != glyphs.unread {
+		t.Errorf("StatusIcon() for unread = %q, want %q", got, glyphs.unread)
+	}
+	if got := read.StatusIcon(); got != glyphs.read {
+		t.Errorf("StatusIcon() for read = %q, want %q", got, glyphs.read)
+	}
+}
+
+func TestUpdatedSinceRead(t *testing.T) {
+	lastRead := time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		n    Notification
+		want bool
+	}{
+		{"never read", Notification{Unread: true, UpdatedAt: lastRead}, false},
+		{"new activity", Notification{Unread: true, UpdatedAt: lastRead.Add(time.Hour), LastReadAt: &lastRead}, true},
+		{"read since", Notification{UpdatedAt: lastRead.Add(time.Hour), LastReadAt: &lastRead}, false},
+		{"no activity", Notification{Unread: true, UpdatedAt: lastRead, LastReadAt: &lastRead}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.n.UpdatedSinceRead(); got != tt.want {
+			t.Errorf("%s: UpdatedSinceRead() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	updated := tests[1].n
+	if got := updated.StatusIcon(); got != glyphs.updated {
+		t.Errorf("StatusIcon() for new activity = %q, want %q", got, glyphs.updated)
+	}
+}
+
+func TestFormattedDate(t *testing.T) {
+	n := Notification{UpdatedAt: time.Date(2024, time.March, 5, 9, 7, 0, 0, time.UTC)}
+	if got, want := n.FormattedDate(), "03-05 09:07"; got != want {
+		t.Errorf("FormattedDate() = %q, want %q", got, want)
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	n := Notification{Repository: Repository{FullName: "owner/repo"}}
+	if got, want := n.RepoName(), "owner/repo"; got != want {
+		t.Errorf("RepoName() = %q, want %q", got, want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a longer title", 10, "a longe..."},
+		{"🚀 Fix the bug", 10, "🚀 Fix ..."},
+		{"日本語のタイトル", 10, "日本語..."},
+		{"日本語のタイトル", 9, "日本語..."},
+		{"abcdef", 3, "abc"},
+		{"abcdef", 0, ""},
+		{"abcdef", -5, ""},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if width := ansi.StringWidth(got); width > max(tt.width, 0) {
+			t.Errorf("truncate(%q, %d) is %d columns wide", tt.s, tt.width, width)
+		}
+	}
+}
+
+func TestPadRightAlignsWideCharacters(t *testing.T) {
+	for _, s := range []string{"owner/repo", "日本/リポジトリ", "🚀/rocket"} {
+		if width := ansi.StringWidth(padRight(s, 20)); width != 20 {
+			t.Errorf("padRight(%q, 20) is %d columns wide, want 20", s, width)
+		}
+	}
+}
+
+// testModel returns a model showing notifications with the given IDs, in
+// that order, with the cursor on selected
+func testModel(selected int, ids ...string) Model {
+	m := Model{
+		sortMode:      sortNewest,
+		selectedIndex: selected,
+		selected:      make(map[string]bool),
+		subjectStates: make(map[string]subjectState),
+	}
+	m.config.CursorAfterMark = cursorNext
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range ids {
+		m.notifications = append(m.notifications, Notification{
+			ID:        id,
+			Unread:    true,
+			UpdatedAt: start.Add(-time.Duration(i) * time.Hour), // newest first
+		})
+	}
+	return m
+}
+
+func visibleIDs(m Model) []string {
+	var ids []string
+	for _, notification := range m.visibleNotifications() {
+		ids = append(ids, notification.ID)
+	}
+	return ids
+}
+
+func TestNotificationMarkedRemovesAndClamps(t *testing.T) {
+	tests := []struct {
+		name         string
+		selected     int
+		mark         string
+		afterMark    string
+		wantIDs      []string
+		wantSelected int
+	}{
+		{"first", 0, "a", cursorNext, []string{"b", "c"}, 0},
+		{"middle moves to next", 1, "b", cursorNext, []string{"a", "c"}, 1},
+		{"last clamps", 2, "c", cursorNext, []string{"a", "b"}, 1},
+		{"middle moves to previous", 1, "b", cursorPrevious, []string{"a", "c"}, 0},
+		{"first with previous stays", 0, "a", cursorPrevious, []string{"b", "c"}, 0},
+		{"another row keeps cursor", 2, "a", cursorNext, []string{"b", "c"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testModel(tt.selected, "a", "b", "c")
+			m.config.CursorAfterMark = tt.afterMark
+			m.inFlight = 1
+
+			updated, _ := m.Update(notificationMarkedMsg{id: tt.mark})
+			m = updated.(Model)
+
+			if got := visibleIDs(m); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("notifications = %v, want %v", got, tt.wantIDs)
+			}
+			if m.selectedIndex != tt.wantSelected {
+				t.Errorf("selectedIndex = %d, want %d", m.selectedIndex, tt.wantSelected)
+			}
+			if m.inFlight != 0 {
+				t.Errorf("inFlight = %d, want 0", m.inFlight)
+			}
+		})
+	}
+}
+
+func TestNotificationMarkedLastOne(t *testing.T) {
+	m := testModel(0, "a")
+	updated, _ := m.Update(notificationMarkedMsg{id: "a"})
+	m = updated.(Model)
+	if len(m.notifications) != 0 || m.selectedIndex != 0 {
+		t.Errorf("got %d notifications with selectedIndex %d, want none with 0",
+			len(m.notifications), m.selectedIndex)
+	}
+}
+
+func TestNotificationMarkedKeep(t *testing.T) {
+	m := testModel(1, "a", "b", "c")
+	updated, _ := m.Update(notificationMarkedMsg{id: "b", keep: true})
+	m = updated.(Model)
+	if got := visibleIDs(m); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("notifications = %v, want all three kept", got)
+	}
+	if m.notifications[1].Unread {
+		t.Error("kept notification is still unread")
+	}
+	if m.selectedIndex != 1 {
+		t.Errorf("selectedIndex = %d, want 1", m.selectedIndex)
+	}
+}
+
+func TestBulkMarkedKeepsCursor(t *testing.T) {
+	m := testModel(2, "a", "b", "c", "d", "e")
+	m.selected["b"] = true
+	m.selected["d"] = true
+
+	updated, _ := m.Update(bulkMarkedMsg{marked: []string{"b", "d"}})
+	m = updated.(Model)
+
+	if got := visibleIDs(m); !slices.Equal(got, []string{"a", "c", "e"}) {
+		t.Fatalf("notifications = %v, want [a c e]", got)
+	}
+	if current, _ := m.selectedNotification(); current.ID != "c" {
+		t.Errorf("cursor on %q, want it to stay on c", current.ID)
+	}
+	if len(m.selected) != 0 {
+		t.Errorf("selected = %v, want it cleared", m.selected)
+	}
+}
+
+func TestSelectedOrCurrentFollowsView(t *testing.T) {
+	m := testModel(1, "a", "b", "c")
+	ids := func() []string {
+		var ids []string
+		for _, notification := range m.selectedOrCurrent() {
+			ids = append(ids, notification.ID)
+		}
+		return ids
+	}
+
+	if got := ids(); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("nothing selected = %v, want the highlighted [b]", got)
+	}
+
+	m.selected["c"] = true
+	m.selected["a"] = true
+	if got := ids(); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("newest first = %v, want [a c]", got)
+	}
+	m.sortMode = sortOldest
+	if got := ids(); !slices.Equal(got, []string{"c", "a"}) {
+		t.Errorf("oldest first = %v, want [c a]", got)
+	}
+}
+
+func TestIsBot(t *testing.T) {
+	config := defaultConfig()
+	tests := []struct {
+		login       string
+		accountType string
+		want        bool
+	}{
+		{"dependabot[bot]", "Bot", true},
+		{"dependabot", "Bot", true}, // as GraphQL reports it
+		{"renovate[bot]", "", true},
+		{"octocat", "User", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := config.isBot(tt.login, tt.accountType); got != tt.want {
+			t.Errorf("isBot(%q, %q) = %v, want %v", tt.login, tt.accountType, got, tt.want)
+		}
+	}
+}
+
+func TestHideBotsKeepsUnknownAuthors(t *testing.T) {
+	m := testModel(0, "a", "b", "c")
+	m.config = defaultConfig()
+	for i, url := range []string{"u/a", "u/b", "u/c"} {
+		m.notifications[i].Subject.URL = url
+	}
+	m.subjectStates["u/a"] = subjectState{author: "octocat", authorType: "User"}
+	m.subjectStates["u/b"] = subjectState{author: "dependabot[bot]", authorType: "Bot"}
+	m.hideBots = true
+	if got := visibleIDs(m); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("visible = %v, want [a c]", got)
+	}
+}
+
+func TestRepoStatsByUnread(t *testing.T) {
+	m := testModel(0, "a", "b", "c", "d")
+	for i, repo := range []string{"o/busy", "o/busy", "o/quiet", "o/unread"} {
+		m.notifications[i].Repository.FullName = repo
+	}
+	m.notifications[0].Unread = false
+	m.notifications[1].Unread = false
+
+	want := []repoStats{
+		{name: "o/quiet", unread: 1, total: 1},
+		{name: "o/unread", unread: 1, total: 1},
+		{name: "o/busy", unread: 0, total: 2},
+	}
+	if got := m.repoStatsByUnread(); !slices.Equal(got, want) {
+		t.Errorf("repoStatsByUnread() = %v, want %v", got, want)
+	}
+}
+
+func TestNoColorRendersPlainAlignedRows(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.Ascii)
+	applyTheme(darkTheme)
+
+	m := testModel(0, "a", "b")
+	m.config = defaultConfig()
+	m.notifications[0].Reason = "review_requested"
+	m.notifications[1].Reason = "subscribed"
+	m.terminalWidth = 120
+	m.terminalHeight = 20
+	m.loading = false
+
+	view := m.View()
+	if strings.Contains(view, "\x1b[") {
+		t.Fatalf("view has escape codes:\n%q", view)
+	}
+	first := m.formatNotificationLine(m.notifications[0], 0)
+	second := m.formatNotificationLine(m.notifications[1], 1)
+	if ansi.StringWidth(first) != ansi.StringWidth(second) {
+		t.Errorf("rows differ in width:\n%q\n%q", first, second)
+	}
+}
+
+func TestHighlightSearch(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.ANSI)
+	applyTheme(darkTheme)
+
+	m := Model{searchQuery: "fix"}
+	got := m.highlightSearch("Fix the prefix")
+	if want := matchStyle.Render("Fix") + " the pre" + matchStyle.Render("fix"); got != want {
+		t.Errorf("highlightSearch() = %q, want %q", got, want)
+	}
+	if ansi.Strip(got) != "Fix the prefix" {
+		t.Errorf("highlighting changed the text: %q", ansi.Strip(got))
+	}
+}
+
+func TestHelpLineFollowsMode(t *testing.T) {
+	m := testModel(0, "a")
+	m.config = defaultConfig()
+	if got := m.helpLine(); !strings.Contains(got, "r:Mark Read") {
+		t.Errorf("list help = %q, want the list's keys", got)
+	}
+
+	tests := []struct {
+		name string
+		set  func(*Model)
+		want string
+	}{
+		{"confirming", func(m *Model) { m.confirming = true }, "y:Yes  n:No"},
+		{"searching", func(m *Model) { m.searchMode = true }, "Enter:Apply  Esc:Cancel"},
+		{"jumping", func(m *Model) { m.jumpBuffer = "1" }, "Enter:Jump  Esc:Cancel"},
+		{"summary", func(m *Model) { m.showingSummary = true }, "Esc:Back"},
+	}
+	for _, tt := range tests {
+		m := m
+		tt.set(&m)
+		if got := m.helpLine(); !strings.Contains(got, tt.want) || strings.Contains(got, "Mark Read") {
+			t.Errorf("%s help = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	m.showingSummary = true
+	if got := m.helpLine(); !strings.Contains(got, "Reload") {
+		t.Errorf("summary help = %q, want Reload", got)
+	}
+	m.summaryPlain = true
+	if got := m.helpLine(); strings.Contains(got, "Reload") {
+		t.Errorf("raw JSON help = %q, want no Reload", got)
+	}
+
+	m.showingSummary = false
+	m.showingOverview = true
+	if got := m.helpLine(); !strings.Contains(got, "i/Tab/Esc/q:Back") {
+		t.Errorf("overview help = %q, want i/Tab/Esc/q:Back", got)
+	}
+	var err error
+	m.config.keyMap, err = newKeyMap(map[string][]string{actionBack: {"backspace"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.helpLine(); !strings.Contains(got, "i/Tab/backspace/q:Back") {
+		t.Errorf("overview help with back rebound = %q, want i/Tab/backspace/q:Back", got)
+	}
+}
+
+func TestSnoozeWakeTimes(t *testing.T) {
+	wednesday := time.Date(2024, time.March, 6, 17, 30, 0, 0, time.Local)
+	monday := time.Date(2024, time.March, 11, 8, 0, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"tomorrow", snoozeOptions[2].wake(wednesday), time.Date(2024, time.March, 7, 9, 0, 0, 0, time.Local)},
+		{"next week", nextWeek(wednesday), time.Date(2024, time.March, 11, 9, 0, 0, 0, time.Local)},
+		{"next week from a Monday", nextWeek(monday), time.Date(2024, time.March, 18, 9, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestMarkAllReadConfirmation(t *testing.T) {
+	for _, confirm := range []bool{true, false} {
+		m := testModel(0, "a", "b")
+		m.config = defaultConfig()
+		m.config.Confirmations.BulkActions = confirm
+
+		updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlA})
+		m = updated.(Model)
+		if m.confirming != confirm {
+			t.Errorf("bulk_actions %v: confirming = %v", confirm, m.confirming)
+		}
+		if confirm {
+			continue
+		}
+		if cmd == nil {
+			t.Fatal("bulk_actions false: no command to start marking")
+		}
+		start, ok := cmd().(bulkMarkStartMsg)
+		if !ok || !slices.Equal(start.ids, []string{"a", "b"}) {
+			t.Errorf("bulk_actions false: got %#v, want a bulkMarkStartMsg for [a b]", start)
+		}
+	}
+}
+
+func TestLoadConfigRejectsShortRefreshInterval(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "ghn"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "ghn", "config.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A bare number has no unit, so it mustn't be read as nanoseconds
+	for _, content := range []string{"refresh_interval: 60\n", "refresh_interval: 60ns\n", "refresh_interval: 500ms\n"} {
+		write(content)
+		if _, err := loadConfig(); err == nil {
+			t.Errorf("loadConfig() with %q succeeded, want an error", content)
+		}
+	}
+
+	write("refresh_interval: 60s\n")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if cfg.RefreshInterval != time.Minute {
+		t.Errorf("RefreshInterval = %s, want 1m0s", cfg.RefreshInterval)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// repoPickerEntries lists the repositories in the inbox, busiest first. The
+// first entry is always the unfiltered list.
+func (m Model) repoPickerEntries() []countEntry {
+	entries := []countEntry{{label: "", count: len(m.notifications)}}
+	return append(entries, countBy(m.notifications, func(n Notification) string { return n.RepoName() })...)
+}
+
+// openRepoPicker shows the picker with the cursor on the active filter
+func (m Model) openRepoPicker() (tea.Model, tea.Cmd) {
+	m.showingRepoPicker = true
+	m.repoPickerIndex = 0
+	for i, entry := range m.repoPickerEntries() {
+		if entry.label == m.repoFilter {
+			m.repoPickerIndex = i
+			break
+		}
+	}
+	return m, nil
+}
+
+// clampRepoPicker keeps the picker's cursor on one of its count entries,
+// which shrink when a refresh drops a repository
+func (m *Model) clampRepoPicker(count int) {
+	if m.repoPickerIndex >= count {
+		m.repoPickerIndex = count - 1
+	}
+	if m.repoPickerIndex < 0 {
+		m.repoPickerIndex = 0
+	}
+}
+
+// handleRepoPickerKey moves through the picker, applying the highlighted
+// repository as the filter on open
+func (m Model) handleRepoPickerKey(action string) (tea.Model, tea.Cmd) {
+	entries := m.repoPickerEntries()
+	m.clampRepoPicker(len(entries))
+	switch action {
+	case actionUp:
+		if m.repoPickerIndex > 0 {
+			m.repoPickerIndex--
+		}
+	case actionDown:
+		if m.repoPickerIndex < len(entries)-1 {
+			m.repoPickerIndex++
+		}
+	case actionTop:
+		m.repoPickerIndex = 0
+	case actionBottom:
+		m.repoPickerIndex = len(entries) - 1
+	case actionOpen:
+		m.showingRepoPicker = false
+		if len(entries) == 0 {
+			return m, nil
+		}
+		nearby := m.selectionNeighbours()
+		m.repoFilter = entries[m.repoPickerIndex].label
+		m.selectByID(nearby...)
+		m.statusMessage = m.filterStatus()
+	case actionBack, actionQuit, actionRepoPicker:
+		m.showingRepoPicker = false
+	}
+	return m, nil
+}
+
+// repoPickerView renders the picker, scrolled to keep the cursor visible
+func (m Model) repoPickerView() string {
+	entries := m.repoPickerEntries()
+	m.clampRepoPicker(len(entries))
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Filter by Repository"))
+	b.WriteString("\n\n")
+
+	height := m.terminalHeight - 5 // title, blank lines and help
+	if height < 1 {
+		height = 1
+	}
+	start := 0
+	if m.repoPickerIndex >= height {
+		start = m.repoPickerIndex - height + 1
+	}
+	end := min(start+height, len(entries))
+
+	for i := start; i < end; i++ {
+		label := entries[i].label
+		if label == "" {
+			label = "All repositories"
+		}
+		line := fmt.Sprintf("%4d  %s", entries[i].count, label)
+		if i == m.repoPickerIndex {
+			b.WriteString(selectedStyle.Render("> " + line))
+		} else {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	keys := m.config.keyMap
+	b.WriteString(dimStyle.Render(strings.Join([]string{
+		keys.Help(actionOpen, "Filter"),
+		keys.Help(actionBack, "Cancel"),
+	}, "  ")))
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// repoStats is one row of the repositories screen
+type repoStats struct {
+	name   string
+	unread int
+	total  int
+}
+
+// repoStatsByUnread tallies the inbox by repository, most unread first, then
+// busiest first
+func (m Model) repoStatsByUnread() []repoStats {
+	byName := make(map[string]*repoStats)
+	var stats []*repoStats
+	for _, notification := range m.notifications {
+		name := notification.RepoName()
+		entry, ok := byName[name]
+		if !ok {
+			entry = &repoStats{name: name}
+			byName[name] = entry
+			stats = append(stats, entry)
+		}
+		entry.total++
+		if notification.Unread {
+			entry.unread++
+		}
+	}
+
+	result := make([]repoStats, len(stats))
+	for i, entry := range stats {
+		result[i] = *entry
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].unread != result[j].unread {
+			return result[i].unread > result[j].unread
+		}
+		if result[i].total != result[j].total {
+			return result[i].total > result[j].total
+		}
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+// openRepos shows the repositories screen with the cursor on the filtered
+// repository, if any
+func (m Model) openRepos() (tea.Model, tea.Cmd) {
+	m.showingRepos = true
+	m.reposIndex = 0
+	for i, entry := range m.repoStatsByUnread() {
+		if entry.name == m.repoFilter {
+			m.reposIndex = i
+			break
+		}
+	}
+	return m, nil
+}
+
+// handleReposKey moves through the repositories, filtering the list to the
+// highlighted one on open
+func (m Model) handleReposKey(action string) (tea.Model, tea.Cmd) {
+	stats := m.repoStatsByUnread()
+	switch action {
+	case actionUp:
+		if m.reposIndex > 0 {
+			m.reposIndex--
+		}
+	case actionDown:
+		if m.reposIndex < len(stats)-1 {
+			m.reposIndex++
+		}
+	case actionTop:
+		m.reposIndex = 0
+	case actionBottom:
+		m.reposIndex = max(len(stats)-1, 0)
+	case actionOpen:
+		m.showingRepos = false
+		if len(stats) == 0 {
+			return m, nil
+		}
+		nearby := m.selectionNeighbours()
+		m.repoFilter = stats[m.reposIndex].name
+		m.selectByID(nearby...)
+		m.statusMessage = m.filterStatus()
+	case actionBack, actionQuit, actionRepositories:
+		m.showingRepos = false
+	}
+	return m, nil
+}
+
+// reposView renders the repositories screen, scrolled to keep the cursor
+// visible
+func (m Model) reposView() string {
+	stats := m.repoStatsByUnread()
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Repositories"))
+	b.WriteString("\n\n")
+	b.WriteString(headerStyle.Render(fmt.Sprintf(" %6s %6s  %s", "Unread", "Total", "Repository")))
+	b.WriteString("\n")
+
+	height := m.terminalHeight - 6 // title, header, blank lines and help
+	if height < 1 {
+		height = 1
+	}
+	start := 0
+	if m.reposIndex >= height {
+		start = m.reposIndex - height + 1
+	}
+	end := min(start+height, len(stats))
+
+	for i := start; i < end; i++ {
+		unread := fmt.Sprintf("%6d", stats[i].unread)
+		if stats[i].unread > 0 && i != m.reposIndex {
+			unread = unreadStyle.Render(unread)
+		}
+		line := fmt.Sprintf("%s %6d  %s", unread, stats[i].total, stats[i].name)
+		if i == m.reposIndex {
+			b.WriteString(selectedStyle.Render("> " + line))
+		} else {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	keys := m.config.keyMap
+	b.WriteString(dimStyle.Render(strings.Join([]string{
+		keys.Help(actionOpen, "Filter"),
+		keys.Help(actionBack, "Back"),
+	}, "  ")))
+	return b.String()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// snoozePath returns where snoozed notification IDs are kept, alongside the
+// notification cache
+func snoozePath() (string, error) {
+	path, err := cachePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "snooze.json"), nil
+}
+
+// loadSnoozes reads the wake time of each snoozed notification, dropping any
+// that have already passed. A missing or corrupt file means nothing is
+// snoozed.
+func loadSnoozes(now time.Time) map[string]time.Time {
+	snoozes := make(map[string]time.Time)
+	path, err := snoozePath()
+	if err != nil {
+		return snoozes
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snoozes
+	}
+	if err := json.Unmarshal(data, &snoozes); err != nil {
+		return make(map[string]time.Time)
+	}
+	expireSnoozes(snoozes, now)
+	return snoozes
+}
+
+// saveSnoozes writes the snoozes, replacing the previous file
+func saveSnoozes(snoozes map[string]time.Time) error {
+	path, err := snoozePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snoozes)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// expireSnoozes removes snoozes whose wake time has passed and reports
+// whether any were removed
+func expireSnoozes(snoozes map[string]time.Time, now time.Time) bool {
+	expired := false
+	for id, wake := range snoozes {
+		if !now.Before(wake) {
+			delete(snoozes, id)
+			expired = true
+		}
+	}
+	return expired
+}
+
+// snoozeOption is one choice in the snooze menu
+type snoozeOption struct {
+	label string
+	wake  func(now time.Time) time.Time
+}
+
+// snoozeOptions are the snooze menu's choices, in the local time zone
+var snoozeOptions = []snoozeOption{
+	{"1 hour", func(now time.Time) time.Time { return now.Add(time.Hour) }},
+	{"4 hours", func(now time.Time) time.Time { return now.Add(4 * time.Hour) }},
+	{"Tomorrow 9am", func(now time.Time) time.Time { return morning(now.AddDate(0, 0, 1)) }},
+	{"Next week", nextWeek},
+}
+
+// morning is 9am on day's date
+func morning(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 9, 0, 0, 0, day.Location())
+}
+
+// nextWeek is 9am next Monday, a full week away when it's already Monday
+func nextWeek(now time.Time) time.Time {
+	days := (int(time.Monday) - int(now.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return morning(now.AddDate(0, 0, days))
+}
+
+// openSnooze shows the snooze menu for the highlighted notification
+func (m Model) openSnooze() (tea.Model, tea.Cmd) {
+	notification, ok := m.selectedNotification()
+	if !ok {
+		return m, nil
+	}
+	m.showingSnooze = true
+	m.snoozeIndex = 0
+	m.snoozeTarget = notification
+	return m, nil
+}
+
+// handleSnoozeKey moves through the snooze menu, snoozing until the
+// highlighted choice on open
+func (m Model) handleSnoozeKey(action string) (tea.Model, tea.Cmd) {
+	switch action {
+	case actionUp:
+		if m.snoozeIndex > 0 {
+			m.snoozeIndex--
+		}
+	case actionDown:
+		if m.snoozeIndex < len(snoozeOptions)-1 {
+			m.snoozeIndex++
+		}
+	case actionTop:
+		m.snoozeIndex = 0
+	case actionBottom:
+		m.snoozeIndex = len(snoozeOptions) - 1
+	case actionOpen:
+		m.showingSnooze = false
+		wake := snoozeOptions[m.snoozeIndex].wake(time.Now())
+		m.snoozed[m.snoozeTarget.ID] = wake
+		delete(m.selected, m.snoozeTarget.ID)
+		m.clampSelection()
+		m.statusMessage = "Snoozed until " + wake.Format("Mon 15:04")
+		return m, saveSnoozesCmd(m.snoozed)
+	case actionBack, actionQuit, actionSnooze:
+		m.showingSnooze = false
+	}
+	return m, nil
+}
+
+// snoozeView renders the snooze menu, with when each choice wakes
+func (m Model) snoozeView() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Snooze"))
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render(truncate(m.snoozeTarget.Subject.Title, max(m.terminalWidth-2, 10))))
+	b.WriteString("\n\n")
+
+	now := time.Now()
+	for i, option := range snoozeOptions {
+		line := fmt.Sprintf("%-14s %s", option.label, dimStyle.Render(option.wake(now).Format("Mon 01-02 15:04")))
+		if i == m.snoozeIndex {
+			b.WriteString(selectedStyle.Render("> " + line))
+		} else {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	keys := m.config.keyMap
+	b.WriteString(dimStyle.Render(strings.Join([]string{
+		keys.Help(actionOpen, "Snooze"),
+		keys.Help(actionBack, "Cancel"),
+	}, "  ")))
+	return b.String()
+}
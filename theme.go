@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme bundles the colors used across the UI
+type Theme struct {
+	Title         lipgloss.Color
+	HeaderText    lipgloss.Color
+	HeaderBg      lipgloss.Color
+	Selected      lipgloss.Color
+	Unread        lipgloss.Color
+	Read          lipgloss.Color
+	Dim           lipgloss.Color
+	Status        lipgloss.Color
+	SummaryBorder lipgloss.Color
+	Mention       lipgloss.Color
+	TeamMention   lipgloss.Color
+	Review        lipgloss.Color
+	Important     lipgloss.Color
+	Match         lipgloss.Color
+	Open          lipgloss.Color
+	Closed        lipgloss.Color
+	Merged        lipgloss.Color
+	Warning       lipgloss.Color
+}
+
+var (
+	darkTheme = Theme{
+		Title:         lipgloss.Color("#04B575"),
+		HeaderText:    lipgloss.Color("#FAFAFA"),
+		HeaderBg:      lipgloss.Color("#7D56F4"),
+		Selected:      lipgloss.Color("#BD93F9"),
+		Unread:        lipgloss.Color("#FF5F87"),
+		Read:          lipgloss.Color("#50FA7B"),
+		Dim:           lipgloss.Color("#6272A4"),
+		Status:        lipgloss.Color("#8BE9FD"),
+		SummaryBorder: lipgloss.Color("#FF79C6"),
+		Mention:       lipgloss.Color("#F1FA8C"),
+		TeamMention:   lipgloss.Color("#FFB86C"),
+		Review:        lipgloss.Color("#8BE9FD"),
+		Important:     lipgloss.Color("#FFAF00"),
+		Match:         lipgloss.Color("#FFFF5F"),
+		Open:          lipgloss.Color("#50FA7B"),
+		Closed:        lipgloss.Color("#FF5555"),
+		Merged:        lipgloss.Color("#BD93F9"),
+		Warning:       lipgloss.Color("#FF5555"),
+	}
+
+	// Darker, more saturated variants that stay readable on a white background
+	lightTheme = Theme{
+		Title:         lipgloss.Color("#00875F"),
+		HeaderText:    lipgloss.Color("#FFFFFF"),
+		HeaderBg:      lipgloss.Color("#5A3FC0"),
+		Selected:      lipgloss.Color("#6B3FA0"),
+		Unread:        lipgloss.Color("#D7005F"),
+		Read:          lipgloss.Color("#008700"),
+		Dim:           lipgloss.Color("#6C6C8A"),
+		Status:        lipgloss.Color("#005F87"),
+		SummaryBorder: lipgloss.Color("#AF005F"),
+		Mention:       lipgloss.Color("#875F00"),
+		TeamMention:   lipgloss.Color("#AF5F00"),
+		Review:        lipgloss.Color("#005FAF"),
+		Important:     lipgloss.Color("#D75F00"),
+		Match:         lipgloss.Color("#AF8700"),
+		Open:          lipgloss.Color("#008700"),
+		Closed:        lipgloss.Color("#D70000"),
+		Merged:        lipgloss.Color("#6B3FA0"),
+		Warning:       lipgloss.Color("#D70000"),
+	}
+)
+
+// themeNames are the accepted values for the theme setting
+var themeNames = []string{"auto", "dark", "light"}
+
+func validTheme(name string) bool {
+	for _, known := range themeNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// themeByName resolves a theme setting. "auto" (or empty) picks by the
+// terminal's background color.
+func themeByName(name string) (Theme, error) {
+	switch name {
+	case "", "auto":
+		if lipgloss.HasDarkBackground() {
+			return darkTheme, nil
+		}
+		return lightTheme, nil
+	case "dark":
+		return darkTheme, nil
+	case "light":
+		return lightTheme, nil
+	default:
+		return Theme{}, fmt.Errorf("unknown theme %q, expected one of %s", name, strings.Join(themeNames, ", "))
+	}
+}
+
+// Styles, built from the active theme by applyTheme
+var (
+	titleStyle      lipgloss.Style
+	headerStyle     lipgloss.Style
+	selectedStyle   lipgloss.Style
+	unreadStyle     lipgloss.Style
+	readStyle       lipgloss.Style
+	dimStyle        lipgloss.Style
+	statusStyle     lipgloss.Style
+	summaryBoxStyle lipgloss.Style
+	warningStyle    lipgloss.Style
+	importantStyle  lipgloss.Style
+	matchStyle      lipgloss.Style // search matches
+
+	// Reasons that warrant standing out; team mentions are kept distinct
+	// from personal mentions, and review requests from both
+	reasonStyles map[string]lipgloss.Style
+
+	// Issue and pull request states, as shown in the list
+	stateStyles map[string]lipgloss.Style
+)
+
+func applyTheme(theme Theme) {
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(theme.Title)
+
+	headerStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(theme.HeaderText).
+		Background(theme.HeaderBg).
+		Padding(0, 1)
+
+	selectedStyle = lipgloss.NewStyle().
+		Foreground(theme.Selected).
+		Bold(true)
+
+	unreadStyle = lipgloss.NewStyle().
+		Foreground(theme.Unread)
+
+	readStyle = lipgloss.NewStyle().
+		Foreground(theme.Read)
+
+	dimStyle = lipgloss.NewStyle().
+		Foreground(theme.Dim)
+
+	statusStyle = lipgloss.NewStyle().
+		Foreground(theme.Status)
+
+	warningStyle = lipgloss.NewStyle().
+		Foreground(theme.Warning).
+		Bold(true)
+
+	importantStyle = lipgloss.NewStyle().
+		Foreground(theme.Important).
+		Bold(true)
+
+	matchStyle = lipgloss.NewStyle().
+		Foreground(theme.Match).
+		Bold(true)
+
+	summaryBoxStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.SummaryBorder).
+		Padding(1, 2)
+
+	reasonStyles = map[string]lipgloss.Style{
+		"mention":            lipgloss.NewStyle().Foreground(theme.Mention),
+		"team_mention":       lipgloss.NewStyle().Foreground(theme.TeamMention),
+		"review_requested":   lipgloss.NewStyle().Foreground(theme.Review),
+		"approval_requested": lipgloss.NewStyle().Foreground(theme.Review),
+	}
+
+	stateStyles = map[string]lipgloss.Style{
+		"open":   lipgloss.NewStyle().Foreground(theme.Open),
+		"closed": lipgloss.NewStyle().Foreground(theme.Closed),
+		"merged": lipgloss.NewStyle().Foreground(theme.Merged),
+	}
+}
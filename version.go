@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Build information, set at build time with e.g.
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%Y-%m-%d)"
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// versionString describes the build, e.g. "ghn v1.2.3 (abc1234, 2024-05-01)"
+func versionString() string {
+	v := version
+	if v != "dev" && !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return fmt.Sprintf("ghn %s (%s, %s)", v, commit, date)
+}